@@ -0,0 +1,97 @@
+package binson
+
+import (
+    "bytes"
+    "encoding/base64"
+    "encoding/pem"
+    "fmt"
+    "hash/crc32"
+    "strconv"
+    "strings"
+)
+
+// crcHeader is the armor header holding the CRC-32 of the Binson bytes.
+const crcHeader = "Crc32"
+
+// Returns b as a PEM-style text block with the given label, for pasting
+// into config files and messages. The block carries a CRC-32 header.
+func Armor(b Binson, label string) []byte {
+    // Without caller headers encoding to memory can not fail.
+    text, _ := ArmorHeaders(b, label, nil)
+    return text
+}
+
+// Returns b as a PEM-style text block with the given label and headers.
+// Header names must not contain ':' or line breaks, values must not contain
+// line breaks, and the name Crc32 is reserved.
+func ArmorHeaders(b Binson, label string, headers map[string]string) ([]byte, error) {
+    data := b.ToBytes()
+    all := make(map[string]string, len(headers) + 1)
+    for k, v := range headers {
+        if k == crcHeader || strings.ContainsAny(k, ":\r\n") || strings.ContainsAny(v, "\r\n") {
+            return nil, fmt.Errorf("Bad armor header: %q", k)
+        }
+        all[k] = v
+    }
+    all[crcHeader] = fmt.Sprintf("%08x", crc32.ChecksumIEEE(data))
+    var buf bytes.Buffer
+    err := pem.Encode(&buf, &pem.Block{
+        Type: label,
+        Headers: all,
+        Bytes: data,
+    })
+    if err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+// Parses the first armored block in data and returns the object and its
+// label.
+func Dearmor(data []byte) (Binson, string, error) {
+    b, label, _, err := DearmorHeaders(data)
+    return b, label, err
+}
+
+// Parses the first armored block in data and returns the object, its label
+// and its headers. The CRC is checked if the block has one.
+func DearmorHeaders(data []byte) (Binson, string, map[string]string, error) {
+    block, _ := pem.Decode(data)
+    if block == nil {
+        return nil, "", nil, fmt.Errorf("No armored block found")
+    }
+    headers := make(map[string]string, len(block.Headers))
+    for k, v := range block.Headers {
+        headers[k] = v
+    }
+    if crc, ok := headers[crcHeader]; ok {
+        want, err := strconv.ParseUint(crc, 16, 32)
+        if err != nil {
+            return nil, "", nil, fmt.Errorf("Bad CRC header: %s", crc)
+        }
+        if uint32(want) != crc32.ChecksumIEEE(block.Bytes) {
+            return nil, "", nil, fmt.Errorf("CRC mismatch")
+        }
+        delete(headers, crcHeader)
+    }
+    b, err := Parse(block.Bytes)
+    if err != nil {
+        return nil, "", nil, err
+    }
+    return b, block.Type, headers, nil
+}
+
+// Returns b as a single line of unpadded base64url, for URLs and
+// environment variables.
+func EncodeString(b Binson) string {
+    return base64.RawURLEncoding.EncodeToString(b.ToBytes())
+}
+
+// Parses a string written by EncodeString.
+func DecodeString(s string) (Binson, error) {
+    data, err := base64.RawURLEncoding.DecodeString(s)
+    if err != nil {
+        return nil, err
+    }
+    return Parse(data)
+}
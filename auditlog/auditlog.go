@@ -0,0 +1,199 @@
+// Package auditlog is a tamper-evident log of Binson records.
+//
+// Each entry is a Binson object stored back to back in a file:
+//
+//     {"body": record, "prev": hash, "seq": n, "sig": signature}
+//
+// where prev is the SHA-256 hash of the canonical bytes of the previous
+// entry (32 zero bytes for the first) and sig is an optional ed25519
+// signature of the canonical bytes of the entry without sig. Changing,
+// reordering or removing entries before the last one breaks the chain,
+// which Verify detects. Removing entries from the end of the log leaves a
+// valid chain and can not be detected from the log alone; keep the entry
+// count or the hash of the last entry elsewhere to detect it.
+package auditlog
+
+import (
+    "bytes"
+    "crypto/ed25519"
+    "crypto/sha256"
+    "fmt"
+    "io"
+    "os"
+
+    "github.com/hakanols/binson-go"
+)
+
+// BrokenLinkError reports the first entry of a log that fails verification.
+type BrokenLinkError struct {
+    Seq int64
+    Offset int64
+    Reason string
+}
+
+func (e *BrokenLinkError) Error() string {
+    return fmt.Sprintf("Broken link at entry %d (offset %d): %s", e.Seq, e.Offset, e.Reason)
+}
+
+// Writer appends entries to an audit log file.
+type Writer struct {
+    file *os.File
+    key ed25519.PrivateKey
+    prev [sha256.Size]byte
+    seq int64
+    size int64
+    err error
+}
+
+// Opens the audit log at path for appending, creating it if needed. If key
+// is not nil every new entry is signed. The existing entries are read to
+// continue the chain but not verified, see Verify. Data after the last
+// entry that holds no complete entry, left by a crash during Append, is
+// removed. Other unreadable data gives a *BrokenLinkError with the offset
+// where the readable log ends.
+func Open(path string, key ed25519.PrivateKey) (*Writer, error) {
+    file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+    if err != nil {
+        return nil, err
+    }
+    w := &Writer{file: file, key: key}
+    s := binson.NewScanner(file)
+    var end int64
+    for s.Scan() {
+        w.prev = sha256.Sum256(s.Bytes())
+        w.seq++
+        end = s.Offset() + int64(len(s.Bytes()))
+    }
+    if err := s.Err(); err != nil {
+        torn, tornErr := tornTail(file, end)
+        if tornErr != nil {
+            err = tornErr
+        } else if torn {
+            err = truncate(file, end)
+        } else {
+            err = &BrokenLinkError{w.seq, end, err.Error()}
+        }
+        if err != nil {
+            file.Close()
+            return nil, err
+        }
+    }
+    w.size = end
+    return w, nil
+}
+
+// Reports whether the data from offset to the end of file holds no complete
+// entry, as left by a crash during Append.
+func tornTail(file *os.File, offset int64) (bool, error) {
+    info, err := file.Stat()
+    if err != nil {
+        return false, err
+    }
+    s := binson.NewScanner(io.NewSectionReader(file, offset, info.Size() - offset))
+    s.SetResync(true)
+    for s.Scan() {
+        // Objects nested in a torn entry are found too, so only count
+        // objects that look like an entry.
+        entry := s.Binson()
+        if entry.HasBinson("body") && entry.HasBytes("prev") && entry.HasInt("seq") {
+            return false, nil
+        }
+    }
+    return true, s.Err()
+}
+
+func truncate(file *os.File, size int64) error {
+    if err := file.Truncate(size); err != nil {
+        return err
+    }
+    return file.Sync()
+}
+
+// Appends a record and writes it to disk. If writing fails the file is cut
+// back to its previous size. If that fails too the Writer is broken and
+// every later call returns the error.
+func (w *Writer) Append(body binson.Binson) error {
+    if w.err != nil {
+        return w.err
+    }
+    entry := binson.NewBinson().
+        Put("body", body).
+        Put("prev", w.prev[:]).
+        Put("seq", w.seq)
+    if w.key != nil {
+        entry.Put("sig", ed25519.Sign(w.key, entry.ToBytes()))
+    }
+    data := entry.ToBytes()
+    _, err := w.file.Write(data)
+    if err == nil {
+        err = w.file.Sync()
+    }
+    if err != nil {
+        if truncErr := truncate(w.file, w.size); truncErr != nil {
+            w.err = fmt.Errorf("Audit log broken after failed append: %v", err)
+        }
+        return err
+    }
+    w.size += int64(len(data))
+    w.prev = sha256.Sum256(data)
+    w.seq++
+    return nil
+}
+
+// Closes the log file.
+func (w *Writer) Close() error {
+    return w.file.Close()
+}
+
+// Reads an audit log and checks every link. If key is not nil every entry
+// must carry a valid signature by it. Returns the number of entries, or a
+// *BrokenLinkError for the first entry that fails.
+func Verify(r io.Reader, key ed25519.PublicKey) (int64, error) {
+    s := binson.NewScanner(r)
+    var prev [sha256.Size]byte
+    var seq int64
+    var end int64
+    for s.Scan() {
+        entry := s.Binson()
+        broken := func(reason string) error {
+            return &BrokenLinkError{seq, s.Offset(), reason}
+        }
+        if !entry.HasBinson("body") {
+            return seq, broken("Missing body")
+        }
+        link, _ := entry.GetBytes("prev")
+        if !bytes.Equal(link, prev[:]) {
+            return seq, broken("Hash of previous entry does not match")
+        }
+        if n, _ := entry.GetInt("seq"); n != seq || !entry.HasInt("seq") {
+            return seq, broken("Wrong sequence number")
+        }
+        if key != nil {
+            sig, ok := entry.GetBytes("sig")
+            if !ok {
+                return seq, broken("Missing signature")
+            }
+            entry.Remove("sig")
+            if !ed25519.Verify(key, entry.ToBytes(), sig) {
+                return seq, broken("Bad signature")
+            }
+        }
+        prev = sha256.Sum256(s.Bytes())
+        end = s.Offset() + int64(len(s.Bytes()))
+        seq++
+    }
+    if err := s.Err(); err != nil {
+        return seq, &BrokenLinkError{seq, end, err.Error()}
+    }
+    return seq, nil
+}
+
+// Verifies the audit log file at path, see Verify.
+func VerifyFile(path string, key ed25519.PublicKey) (int64, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return 0, err
+    }
+    defer file.Close()
+    return Verify(file, key)
+}
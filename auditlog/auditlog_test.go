@@ -0,0 +1,149 @@
+package auditlog
+
+import (
+    "bytes"
+    "crypto/ed25519"
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/hakanols/binson-go"
+    "github.com/stretchr/testify/assert"
+)
+
+func writeLog(t *testing.T, path string, key ed25519.PrivateKey, count int) {
+    w, err := Open(path, key)
+    assert.Nil(t, err, "Got error")
+    for i := 0; i < count; i++ {
+        assert.Nil(t, w.Append(binson.NewBinson().Put("event", "login").Put("n", i)), "Got error")
+    }
+    assert.Nil(t, w.Close(), "Got error")
+}
+
+func TestAppendVerify(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "audit")
+    writeLog(t, path, nil, 3)
+    writeLog(t, path, nil, 2)
+    n, err := VerifyFile(path, nil)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, int64(5), n, "Wrong count")
+
+    empty := filepath.Join(t.TempDir(), "empty")
+    n, err = VerifyFile(empty, nil)
+    assert.NotNil(t, err, "Should get error for missing file")
+    writeLog(t, empty, nil, 0)
+    n, err = VerifyFile(empty, nil)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, int64(0), n, "Wrong count")
+}
+
+func TestSigned(t *testing.T) {
+    pub, priv, _ := ed25519.GenerateKey(nil)
+    otherPub, _, _ := ed25519.GenerateKey(nil)
+    path := filepath.Join(t.TempDir(), "audit")
+    writeLog(t, path, priv, 3)
+    n, err := VerifyFile(path, pub)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, int64(3), n, "Wrong count")
+    n, err = VerifyFile(path, nil)
+    assert.Nil(t, err, "Got error")
+
+    _, err = VerifyFile(path, otherPub)
+    var broken *BrokenLinkError
+    assert.True(t, errors.As(err, &broken), "Should get broken link")
+    assert.Equal(t, int64(0), broken.Seq, "Wrong entry")
+    assert.Equal(t, "Bad signature", broken.Reason, "Wrong reason")
+
+    unsigned := filepath.Join(t.TempDir(), "audit")
+    writeLog(t, unsigned, nil, 1)
+    _, err = VerifyFile(unsigned, pub)
+    assert.True(t, errors.As(err, &broken), "Should get broken link")
+    assert.Equal(t, "Missing signature", broken.Reason, "Wrong reason")
+}
+
+func entries(t *testing.T, path string) [][]byte {
+    data, _ := os.ReadFile(path)
+    s := binson.NewScanner(bytes.NewReader(data))
+    list := [][]byte{}
+    for s.Scan() {
+        list = append(list, append([]byte{}, s.Bytes()...))
+    }
+    return list
+}
+
+func TestTamper(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "audit")
+    writeLog(t, path, nil, 4)
+    list := entries(t, path)
+
+    changed, _ := binson.Parse(list[2])
+    body, _ := changed.GetBinson("body")
+    body.Put("event", "logout")
+    tampered := [][]byte{list[0], list[1], changed.ToBytes(), list[3]}
+    _, err := Verify(bytes.NewReader(bytes.Join(tampered, nil)), nil)
+    var broken *BrokenLinkError
+    assert.True(t, errors.As(err, &broken), "Should get broken link")
+    assert.Equal(t, int64(3), broken.Seq, "Change shall break the next link")
+    assert.Equal(t, int64(len(list[0]) + len(list[1]) + len(changed.ToBytes())), broken.Offset, "Wrong offset")
+
+    removed := [][]byte{list[0], list[2], list[3]}
+    _, err = Verify(bytes.NewReader(bytes.Join(removed, nil)), nil)
+    assert.True(t, errors.As(err, &broken), "Should get broken link")
+    assert.Equal(t, int64(1), broken.Seq, "Wrong entry")
+
+    truncated := bytes.Join(list, nil)
+    truncated = truncated[:len(truncated) - 3]
+    n, err := Verify(bytes.NewReader(truncated), nil)
+    assert.True(t, errors.As(err, &broken), "Should get broken link")
+    assert.Equal(t, int64(3), n, "Wrong count")
+    assert.Equal(t, int64(3), broken.Seq, "Wrong entry")
+    assert.Equal(t, int64(len(list[0]) + len(list[1]) + len(list[2])), broken.Offset, "Wrong offset")
+}
+
+func TestTornTail(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "audit")
+    writeLog(t, path, nil, 3)
+    list := entries(t, path)
+    data, _ := os.ReadFile(path)
+    os.WriteFile(path, data[:len(data) - 3], 0644)
+
+    writeLog(t, path, nil, 2)
+    n, err := VerifyFile(path, nil)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, int64(4), n, "Torn entry shall be replaced")
+
+    for _, tail := range [][]byte{{0x01, 0x02}, make([]byte, 40)} {
+        os.WriteFile(path, append(bytes.Join(list, nil), tail...), 0644)
+        writeLog(t, path, nil, 1)
+        n, err = VerifyFile(path, nil)
+        assert.Nil(t, err, "Got error")
+        assert.Equal(t, int64(4), n, "Tail without entry shall be removed")
+    }
+
+    garbage := append(append(bytes.Join(list[:2], nil), 0x01, 0x02), list[2]...)
+    os.WriteFile(path, garbage, 0644)
+    _, err = Open(path, nil)
+    var broken *BrokenLinkError
+    assert.True(t, errors.As(err, &broken), "Should get broken link")
+    assert.Equal(t, int64(2), broken.Seq, "Wrong entry")
+    assert.Equal(t, int64(len(list[0]) + len(list[1])), broken.Offset, "Wrong offset")
+    info, _ := os.Stat(path)
+    assert.Equal(t, int64(len(garbage)), info.Size(), "File shall be left unchanged")
+}
+
+func TestFailedAppend(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "audit")
+    writeLog(t, path, nil, 2)
+    w, _ := Open(path, nil)
+    assert.Equal(t, int64(2), w.seq, "Wrong sequence")
+    w.file.Close()
+    assert.NotNil(t, w.Append(binson.NewBinson()), "Should get error")
+    assert.NotNil(t, w.err, "Writer shall be broken")
+    assert.NotNil(t, w.Append(binson.NewBinson()), "Should get error")
+
+    writeLog(t, path, nil, 1)
+    n, err := VerifyFile(path, nil)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, int64(3), n, "Wrong count")
+}
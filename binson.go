@@ -3,11 +3,6 @@
 // See binson.org.
 package binson
 
-import (
-    "fmt"
-    "sort"
-)
-
 // Returns a new empty binson object.
 func NewBinson() Binson {
     b := make(map[binsonString]field)
@@ -17,12 +12,7 @@ func NewBinson() Binson {
 // Returns an ordered list of the field names of this Binson object.
 // Can be used to iterate all fields of this Binson object.
 func (b Binson) FieldNames() []string {
-    keys := make([]string, 0, len(b))
-    for k := range b {
-        keys = append(keys, string(k))
-    }
-    sort.Strings(keys)
-    return keys;
+    return b.sortedKeys()
 }
 
 // Returns true if the Binson object has a field with the given name.
@@ -36,6 +26,33 @@ func (b Binson) Remove(name string) {
     delete(b, binsonString(name))
 }
 
+// Removes all fields, keeping the allocated storage.
+func (b Binson) Reset() {
+    clear(b)
+}
+
+// Returns a deep copy of this Binson object.
+func (b Binson) Clone() Binson {
+    c := make(Binson, len(b))
+    for k, v := range b {
+        c[k] = cloneField(v)
+    }
+    return c
+}
+
+func cloneField(f field) field {
+    switch o := f.(type) {
+        case Binson:
+            return o.Clone()
+        case *BinsonArray:
+            return o.Clone()
+        case binsonBytes:
+            return binsonBytes(append([]byte{}, o...))
+        default:
+            return o
+    }
+}
+
 func (b Binson) HasBinson(name string) bool {
     _, ok := b[binsonString(name)].(Binson)
     return ok
@@ -106,28 +123,22 @@ func (b Binson) GetFloat(name string) (float64, bool) {
     return float64(obj), ok
 }
 
-// Adds a field to this Binson object.
+// Adds a field to this Binson object. Values implementing Marshaler are
+// added as the value they marshal to.
 func (b Binson) Put(name string, value interface{}) (Binson) {
-    key := binsonString(name)
-    switch o := value.(type) {
-        case Binson:
-            b[key] = o
-        case *BinsonArray:
-            b[key] = o
-        case int:
-            b[key] = binsonInt(int64(o))
-        case int64:
-            b[key] = binsonInt(o)
-        case string:
-            b[key] = binsonString(o)
-        case []byte:
-            b[key] = binsonBytes(o)
-        case bool:
-            b[key] = binsonBool(o)
-        case float64:
-            b[key] = binsonFloat(o)
-        default: 
-            panic(fmt.Sprintf("%T is not handeled by Binson", o))
+    if err := b.TryPut(name, value); err != nil {
+        panic(err.Error())
     }
     return b
+}
+
+// Adds a field to this Binson object. Returns an error instead of panicking
+// if the value can not be handled by Binson.
+func (b Binson) TryPut(name string, value interface{}) error {
+    f, err := toField(value)
+    if err != nil {
+        return err
+    }
+    b[binsonString(name)] = f
+    return nil
 }
\ No newline at end of file
@@ -1,9 +1,18 @@
 package binson
 
 import (
+    "errors"
     "fmt"
+    "sort"
 )
 
+// ErrIndexOutOfRange is returned when an index is outside of an array.
+var ErrIndexOutOfRange = errors.New("Index out of range")
+
+func indexError(index int) error {
+    return fmt.Errorf("%w: %d", ErrIndexOutOfRange, index)
+}
+
 // Returns a new empty binson array.
 func NewBinsonArray() *BinsonArray {
     a := BinsonArray([]field{})
@@ -16,8 +25,27 @@ func (a *BinsonArray) Size() int{
 }
 
 // Removes a given field if it exists.
-func (a *BinsonArray) Remove(index int){
+func (a *BinsonArray) Remove(index int) error {
+    if a.inRange(index){
+        return indexError(index)
+    }
     *a = append( (*a)[:index], (*a)[index+1:]...)
+    return nil
+}
+
+// Removes all elements, keeping the allocated storage.
+func (a *BinsonArray) Reset() {
+    clear(*a)
+    *a = (*a)[:0]
+}
+
+// Returns a deep copy of this array.
+func (a *BinsonArray) Clone() *BinsonArray {
+    c := make(BinsonArray, 0, a.Size())
+    for _, v := range *a {
+        c = append(c, cloneField(v))
+    }
+    return &c
 }
 
 func (a *BinsonArray) inRange(index int) bool{
@@ -33,6 +61,9 @@ func (a *BinsonArray) HasArray(index int) bool {
 }
 
 func (a *BinsonArray) GetArray(index int) (*BinsonArray, bool) {
+    if a.inRange(index){
+        return nil, false
+    }
     obj, ok := (*a)[index].(*BinsonArray)
     return obj, ok
 }
@@ -46,6 +77,9 @@ func (a *BinsonArray) HasBinson(index int) bool {
 }
 
 func (a *BinsonArray) GetBinson(index int) (Binson, bool) {
+    if a.inRange(index){
+        return nil, false
+    }
     obj, ok := (*a)[index].(Binson)
     return obj, ok
 }
@@ -59,6 +93,9 @@ func (a *BinsonArray) HasInt(index int) bool {
 }
 
 func (a *BinsonArray) GetInt(index int) (int64, bool) {
+    if a.inRange(index){
+        return 0, false
+    }
     obj, ok := (*a)[index].(binsonInt)
     return int64(obj), ok
 }
@@ -72,6 +109,9 @@ func (a *BinsonArray) HasString(index int) bool {
 }
 
 func (a *BinsonArray) GetString(index int) (string, bool) {
+    if a.inRange(index){
+        return "", false
+    }
     obj, ok := (*a)[index].(binsonString)
     return string(obj), ok
 }
@@ -85,6 +125,9 @@ func (a *BinsonArray) HasBytes(index int) bool {
 }
 
 func (a *BinsonArray) GetBytes(index int) ([]byte, bool) {
+    if a.inRange(index){
+        return nil, false
+    }
     obj, ok := (*a)[index].(binsonBytes)
     return []byte(obj), ok
 }
@@ -98,6 +141,9 @@ func (a *BinsonArray) HasBool(index int) bool {
 }
 
 func (a *BinsonArray) GetBool(index int) (bool, bool) {
+    if a.inRange(index){
+        return false, false
+    }
     obj, ok := (*a)[index].(binsonBool)
     return bool(obj), ok
 }
@@ -111,6 +157,9 @@ func (a *BinsonArray) HasFloat(index int) bool {
 }
 
 func (a *BinsonArray) GetFloat(index int) (float64, bool) {
+    if a.inRange(index){
+        return 0, false
+    }
     obj, ok := (*a)[index].(binsonFloat)
     return float64(obj), ok
 }
@@ -120,28 +169,125 @@ func (a *BinsonArray) addField(value field) *BinsonArray {
     return a
 }
 
-// Adds an element to the array.
+// Adds an element to the array. Values implementing Marshaler are added
+// as the value they marshal to.
 func (a *BinsonArray) Put(value interface{}) (*BinsonArray){
-    switch o := value.(type) {
-        case Binson:
-			a.addField(o)
-        case *BinsonArray:
-			a.addField(o)
-        case int:
-            a.addField(binsonInt(int64(o)))
-        case int64:
-            a.addField(binsonInt(o))
-        case string:
-            a.addField(binsonString(o))
-        case []byte:
-            a.addField(binsonBytes(o))
-        case bool:
-            a.addField(binsonBool(o))
-        case float64:
-            a.addField(binsonFloat(o))
-			
-        default: 
-            panic(fmt.Sprintf("%T is not handeled by Binson", o))
+    if err := a.TryPut(value); err != nil {
+        panic(err.Error())
+    }
+    return a
+}
+
+// Adds an element to the array. Returns an error instead of panicking if
+// the value can not be handled by Binson.
+func (a *BinsonArray) TryPut(value interface{}) error {
+    f, err := toField(value)
+    if err != nil {
+        return err
+    }
+    a.addField(f)
+    return nil
+}
+
+// Adds all values to the array. If any value can not be handled by Binson
+// an error is returned and the array is left unchanged.
+func (a *BinsonArray) TryAppend(values ...interface{}) error {
+    fields := make([]field, 0, len(values))
+    for _, value := range values {
+        f, err := toField(value)
+        if err != nil {
+            return err
+        }
+        fields = append(fields, f)
     }
+    *a = append(*a, fields...)
+    return nil
+}
+
+// Replaces the element at the given index.
+func (a *BinsonArray) Set(index int, value interface{}) error {
+    if a.inRange(index){
+        return indexError(index)
+    }
+    f, err := toField(value)
+    if err != nil {
+        return err
+    }
+    (*a)[index] = f
+    return nil
+}
+
+// Inserts an element before the given index. An index equal to Size
+// appends the element.
+func (a *BinsonArray) Insert(index int, value interface{}) error {
+    if index != a.Size() && a.inRange(index){
+        return indexError(index)
+    }
+    f, err := toField(value)
+    if err != nil {
+        return err
+    }
+    *a = append(*a, nil)
+    copy((*a)[index+1:], (*a)[index:])
+    (*a)[index] = f
+    return nil
+}
+
+// Swaps the elements at the given indexes.
+func (a *BinsonArray) Swap(i int, j int) error {
+    if a.inRange(i){
+        return indexError(i)
+    }
+    if a.inRange(j){
+        return indexError(j)
+    }
+    (*a)[i], (*a)[j] = (*a)[j], (*a)[i]
+    return nil
+}
+
+// Returns a new array with the elements from index from up to, but not
+// including, index to.
+func (a *BinsonArray) Slice(from int, to int) (*BinsonArray, error) {
+    if from < 0 || a.Size() < from {
+        return nil, indexError(from)
+    }
+    if to < from || a.Size() < to {
+        return nil, indexError(to)
+    }
+    s := BinsonArray(append([]field{}, (*a)[from:to]...))
+    return &s, nil
+}
+
+// Adds all elements of other to the end of this array.
+func (a *BinsonArray) AppendAll(other *BinsonArray) (*BinsonArray){
+    *a = append(*a, *other...)
     return a
-}
\ No newline at end of file
+}
+
+// Shortens the array to the given size.
+func (a *BinsonArray) Truncate(size int) error {
+    if size < 0 || a.Size() < size {
+        return indexError(size)
+    }
+    for i := size; i < a.Size(); i++ {
+        (*a)[i] = nil
+    }
+    *a = (*a)[:size]
+    return nil
+}
+
+// Reverses the order of the elements.
+func (a *BinsonArray) Reverse() (*BinsonArray){
+    for i, j := 0, a.Size()-1; i < j; i, j = i+1, j-1 {
+        (*a)[i], (*a)[j] = (*a)[j], (*a)[i]
+    }
+    return a
+}
+
+// Sorts the elements with the given less function. The sort is stable.
+func (a *BinsonArray) Sort(less func(x Value, y Value) bool) (*BinsonArray){
+    sort.SliceStable(*a, func(i, j int) bool {
+        return less(Value{(*a)[i]}, Value{(*a)[j]})
+    })
+    return a
+}
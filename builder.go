@@ -0,0 +1,69 @@
+package binson
+
+// Builder adds fields to a Binson object in a fluent chain. Unlike Put on
+// Binson it does not panic; the first error is recorded and all later calls
+// are ignored.
+type Builder struct {
+    obj Binson
+    err error
+}
+
+// Returns a new Builder for an empty Binson object.
+func NewBuilder() *Builder {
+    return &Builder{obj: NewBinson()}
+}
+
+// Adds a field to the object being built.
+func (b *Builder) Put(name string, value interface{}) *Builder {
+    if b.err == nil {
+        b.err = b.obj.TryPut(name, value)
+    }
+    return b
+}
+
+// Returns the first error recorded by Put, if any.
+func (b *Builder) Err() error {
+    return b.err
+}
+
+// Returns the built object, or nil and the first recorded error.
+func (b *Builder) Binson() (Binson, error) {
+    if b.err != nil {
+        return nil, b.err
+    }
+    return b.obj, nil
+}
+
+// ArrayBuilder adds elements to a Binson array in a fluent chain. Unlike
+// Put on BinsonArray it does not panic; the first error is recorded and all
+// later calls are ignored.
+type ArrayBuilder struct {
+    arr *BinsonArray
+    err error
+}
+
+// Returns a new ArrayBuilder for an empty Binson array.
+func NewArrayBuilder() *ArrayBuilder {
+    return &ArrayBuilder{arr: NewBinsonArray()}
+}
+
+// Adds an element to the array being built.
+func (a *ArrayBuilder) Put(value interface{}) *ArrayBuilder {
+    if a.err == nil {
+        a.err = a.arr.TryPut(value)
+    }
+    return a
+}
+
+// Returns the first error recorded by Put, if any.
+func (a *ArrayBuilder) Err() error {
+    return a.err
+}
+
+// Returns the built array, or nil and the first recorded error.
+func (a *ArrayBuilder) Array() (*BinsonArray, error) {
+    if a.err != nil {
+        return nil, a.err
+    }
+    return a.arr, nil
+}
@@ -0,0 +1,319 @@
+// Package bus is an in-process publish/subscribe bus for Binson messages.
+//
+// Messages are published on named topics and delivered to every
+// subscription of the topic whose filter accepts them. Each subscription
+// has a buffered channel and a policy deciding what happens when the
+// buffer is full. Every subscriber receives its own deep copy of a
+// message, so no subscriber can change what another one sees.
+package bus
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "sync"
+    "sync/atomic"
+
+    "github.com/hakanols/binson-go"
+)
+
+// DefaultBuffer is the channel buffer size used when none is given.
+const DefaultBuffer = 16
+
+// ErrClosed is returned when publishing on a closed bus.
+var ErrClosed = errors.New("Bus closed")
+
+// Policy decides what happens when a subscription's buffer is full.
+type Policy int
+
+const (
+    // Block makes the publisher wait until there is room in the buffer.
+    Block Policy = iota
+    // DropOldest discards the oldest buffered message to make room.
+    DropOldest
+    // DropNewest discards the message being published.
+    DropNewest
+)
+
+// Returns the name of the policy.
+func (p Policy) String() string {
+    switch p {
+        case Block:
+            return "Block"
+        case DropOldest:
+            return "DropOldest"
+        case DropNewest:
+            return "DropNewest"
+        default:
+            return "Unknown"
+    }
+}
+
+// Filter reports whether a message shall be delivered to a subscription.
+// A filter is given the subscription's own copy of the message and must not
+// change it.
+type Filter func(msg binson.Binson) bool
+
+// Returns a filter accepting messages that have a field with the given name.
+func Has(name string) Filter {
+    return func(msg binson.Binson) bool {
+        _, ok := msg.Get(name)
+        return ok
+    }
+}
+
+// Returns a filter accepting messages where the named field is equal to
+// value. Values are compared by their Binson encoding, so value can be of
+// any type accepted by Put. A value Binson can not handle matches nothing.
+func Equals(name string, value interface{}) Filter {
+    want, err := binson.ValueOf(value)
+    if err != nil {
+        return func(msg binson.Binson) bool {
+            return false
+        }
+    }
+    wantBytes := want.ToBytes()
+    return func(msg binson.Binson) bool {
+        got, ok := msg.Get(name)
+        return ok && bytes.Equal(got.ToBytes(), wantBytes)
+    }
+}
+
+// Returns a filter accepting messages accepted by all filters.
+func And(filters ...Filter) Filter {
+    return func(msg binson.Binson) bool {
+        for _, f := range filters {
+            if !f(msg) {
+                return false
+            }
+        }
+        return true
+    }
+}
+
+// Returns a filter accepting messages accepted by any of the filters.
+func Or(filters ...Filter) Filter {
+    return func(msg binson.Binson) bool {
+        for _, f := range filters {
+            if f(msg) {
+                return true
+            }
+        }
+        return false
+    }
+}
+
+// Returns a filter accepting messages not accepted by f.
+func Not(f Filter) Filter {
+    return func(msg binson.Binson) bool {
+        return !f(msg)
+    }
+}
+
+// Options configures a Subscription.
+type Options struct {
+    // Buffer is the size of the channel buffer. Zero means DefaultBuffer.
+    Buffer int
+    // Policy is used when the buffer is full.
+    Policy Policy
+    // Filter selects the messages to deliver. Nil accepts all messages.
+    Filter Filter
+}
+
+// Bus routes published messages to subscriptions. It is safe for
+// concurrent use.
+type Bus struct {
+    mu sync.RWMutex
+    topics map[string][]*Subscription
+    closed bool
+}
+
+// Returns a new empty bus.
+func New() *Bus {
+    return &Bus{topics: map[string][]*Subscription{}}
+}
+
+// Subscription receives the messages of one topic.
+type Subscription struct {
+    bus *Bus
+    topic string
+    policy Policy
+    filter Filter
+    ch chan binson.Binson
+    // Senders hold mu for reading while delivering. Close takes it for
+    // writing after done is closed so ch is closed with no sender left.
+    mu sync.RWMutex
+    done chan struct{}
+    once sync.Once
+    dropped atomic.Uint64
+}
+
+// Adds a subscription to the topic. The subscription on a closed bus is
+// returned already closed.
+func (b *Bus) Subscribe(topic string, opts *Options) *Subscription {
+    s := &Subscription{
+        bus: b,
+        topic: topic,
+        done: make(chan struct{}),
+    }
+    size := DefaultBuffer
+    if opts != nil {
+        if opts.Buffer > 0 {
+            size = opts.Buffer
+        }
+        s.policy = opts.Policy
+        s.filter = opts.Filter
+    }
+    s.ch = make(chan binson.Binson, size)
+
+    b.mu.Lock()
+    if b.closed {
+        b.mu.Unlock()
+        s.shutdown()
+        return s
+    }
+    b.topics[topic] = append(b.topics[topic], s)
+    b.mu.Unlock()
+    return s
+}
+
+// Delivers a copy of msg to every subscription of the topic whose filter
+// accepts it. With the Block policy Publish waits for room in the buffer
+// until ctx is done, in which case the error of ctx is returned and the
+// remaining subscriptions are skipped.
+func (b *Bus) Publish(ctx context.Context, topic string, msg binson.Binson) error {
+    b.mu.RLock()
+    if b.closed {
+        b.mu.RUnlock()
+        return ErrClosed
+    }
+    subs := b.topics[topic]
+    b.mu.RUnlock()
+
+    for _, s := range subs {
+        if err := s.deliver(ctx, msg); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Returns the number of subscriptions of the topic.
+func (b *Bus) Subscribers(topic string) int {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    return len(b.topics[topic])
+}
+
+// Closes all subscriptions. Later calls to Publish return ErrClosed.
+func (b *Bus) Close() {
+    b.mu.Lock()
+    if b.closed {
+        b.mu.Unlock()
+        return
+    }
+    b.closed = true
+    topics := b.topics
+    b.topics = map[string][]*Subscription{}
+    b.mu.Unlock()
+
+    for _, subs := range topics {
+        for _, s := range subs {
+            s.shutdown()
+        }
+    }
+}
+
+func (b *Bus) remove(s *Subscription) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    subs := b.topics[s.topic]
+    for i, other := range subs {
+        if other == s {
+            // Publish may hold the old slice, so build a new one.
+            rest := append(append([]*Subscription{}, subs[:i]...), subs[i+1:]...)
+            if len(rest) == 0 {
+                delete(b.topics, s.topic)
+            } else {
+                b.topics[s.topic] = rest
+            }
+            return
+        }
+    }
+}
+
+func (s *Subscription) deliver(ctx context.Context, msg binson.Binson) error {
+    c := msg.Clone()
+    if s.filter != nil && !s.filter(c) {
+        return nil
+    }
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    select {
+        case <-s.done:
+            return nil
+        default:
+    }
+
+    switch s.policy {
+        case DropNewest:
+            select {
+                case s.ch <- c:
+                default:
+                    s.dropped.Add(1)
+            }
+        case DropOldest:
+            for {
+                select {
+                    case s.ch <- c:
+                        return nil
+                    default:
+                }
+                select {
+                    case <-s.ch:
+                        s.dropped.Add(1)
+                    default:
+                }
+            }
+        default:
+            select {
+                case s.ch <- c:
+                case <-s.done:
+                case <-ctx.Done():
+                    return ctx.Err()
+            }
+    }
+    return nil
+}
+
+// Returns the topic of the subscription.
+func (s *Subscription) Topic() string {
+    return s.topic
+}
+
+// Returns the channel messages are delivered on. The channel is closed
+// when the subscription or the bus is closed.
+func (s *Subscription) C() <-chan binson.Binson {
+    return s.ch
+}
+
+// Returns the number of messages dropped because the buffer was full.
+func (s *Subscription) Dropped() uint64 {
+    return s.dropped.Load()
+}
+
+// Removes the subscription from the bus and closes its channel. Buffered
+// messages can still be received from the channel.
+func (s *Subscription) Close() {
+    s.bus.remove(s)
+    s.shutdown()
+}
+
+func (s *Subscription) shutdown() {
+    s.once.Do(func() {
+        close(s.done)
+        s.mu.Lock()
+        close(s.ch)
+        s.mu.Unlock()
+    })
+}
@@ -0,0 +1,192 @@
+package bus
+
+import (
+    "context"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/hakanols/binson-go"
+    "github.com/stretchr/testify/assert"
+)
+
+func event(kind string, n int) binson.Binson {
+    return binson.NewBinson().Put("kind", kind).Put("n", n)
+}
+
+func receive(s *Subscription) []int64 {
+    got := []int64{}
+    for {
+        select {
+            case msg, ok := <-s.C():
+                if !ok {
+                    return got
+                }
+                n, _ := msg.GetInt("n")
+                got = append(got, n)
+            default:
+                return got
+        }
+    }
+}
+
+func TestFilter(t *testing.T) {
+    b := New()
+    defer b.Close()
+    ctx := context.Background()
+    all := b.Subscribe("events", nil)
+    created := b.Subscribe("events", &Options{Filter: Equals("kind", "created")})
+    either := b.Subscribe("events", &Options{Filter: And(Has("n"), Or(Equals("kind", "deleted"), Equals("n", 3)))})
+    other := b.Subscribe("other", nil)
+    assert.Equal(t, 3, b.Subscribers("events"), "Wrong number of subscribers")
+
+    assert.Nil(t, b.Publish(ctx, "events", event("created", 1)), "Got error")
+    assert.Nil(t, b.Publish(ctx, "events", event("deleted", 2)), "Got error")
+    assert.Nil(t, b.Publish(ctx, "events", event("created", 3)), "Got error")
+    assert.Nil(t, b.Publish(ctx, "events", binson.NewBinson().Put("kind", 1)), "Got error")
+
+    assert.Equal(t, []int64{1, 2, 3, 0}, receive(all), "Wrong messages")
+    assert.Equal(t, []int64{1, 3}, receive(created), "Wrong messages")
+    assert.Equal(t, []int64{2, 3}, receive(either), "Wrong messages")
+    assert.Equal(t, []int64{}, receive(other), "Wrong messages")
+
+    assert.False(t, Equals("kind", struct{}{})(event("x", 1)), "Shall match nothing")
+    assert.True(t, Not(Has("x"))(event("x", 1)), "Shall match")
+}
+
+func TestClone(t *testing.T) {
+    b := New()
+    defer b.Close()
+    s1 := b.Subscribe("t", nil)
+    s2 := b.Subscribe("t", nil)
+    msg := binson.NewBinson().Put("inner", binson.NewBinson().Put("a", 1))
+    assert.Nil(t, b.Publish(context.Background(), "t", msg), "Got error")
+
+    m1 := <-s1.C()
+    inner, _ := m1.GetBinson("inner")
+    inner.Put("a", 2)
+    m2 := <-s2.C()
+    inner, _ = m2.GetBinson("inner")
+    a, _ := inner.GetInt("a")
+    assert.Equal(t, int64(1), a, "Subscriber copies shall be independent")
+    inner, _ = msg.GetBinson("inner")
+    a, _ = inner.GetInt("a")
+    assert.Equal(t, int64(1), a, "Published message shall be unchanged")
+
+    // A filter changing its message shall not affect other subscribers.
+    b.Subscribe("f", &Options{Filter: func(msg binson.Binson) bool {
+        msg.Put("kind", "changed")
+        return false
+    }})
+    s3 := b.Subscribe("f", nil)
+    assert.Nil(t, b.Publish(context.Background(), "f", event("created", 1)), "Got error")
+    kind, _ := (<-s3.C()).GetString("kind")
+    assert.Equal(t, "created", kind, "Filter shall not change other copies")
+}
+
+func TestPolicies(t *testing.T) {
+    b := New()
+    defer b.Close()
+    ctx := context.Background()
+    oldest := b.Subscribe("t", &Options{Buffer: 2, Policy: DropOldest})
+    newest := b.Subscribe("t", &Options{Buffer: 2, Policy: DropNewest})
+    for i := 1; i <= 5; i++ {
+        assert.Nil(t, b.Publish(ctx, "t", event("e", i)), "Got error")
+    }
+    assert.Equal(t, []int64{4, 5}, receive(oldest), "Wrong messages")
+    assert.Equal(t, uint64(3), oldest.Dropped(), "Wrong drop count")
+    assert.Equal(t, []int64{1, 2}, receive(newest), "Wrong messages")
+    assert.Equal(t, uint64(3), newest.Dropped(), "Wrong drop count")
+    assert.Equal(t, "DropOldest", DropOldest.String(), "Wrong name")
+}
+
+func TestBlock(t *testing.T) {
+    b := New()
+    defer b.Close()
+    s := b.Subscribe("t", &Options{Buffer: 1, Policy: Block})
+    assert.Nil(t, b.Publish(context.Background(), "t", event("e", 1)), "Got error")
+
+    ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+    defer cancel()
+    err := b.Publish(ctx, "t", event("e", 2))
+    assert.Equal(t, context.DeadlineExceeded, err, "Shall time out")
+
+    done := make(chan error)
+    go func() {
+        done <- b.Publish(context.Background(), "t", event("e", 3))
+    }()
+    msg := <-s.C()
+    n, _ := msg.GetInt("n")
+    assert.Equal(t, int64(1), n, "Wrong message")
+    assert.Nil(t, <-done, "Got error")
+    msg = <-s.C()
+    n, _ = msg.GetInt("n")
+    assert.Equal(t, int64(3), n, "Wrong message")
+    assert.Equal(t, uint64(0), s.Dropped(), "Shall not drop")
+
+    // Closing a subscription releases a blocked publisher.
+    assert.Nil(t, b.Publish(context.Background(), "t", event("e", 4)), "Got error")
+    go func() {
+        done <- b.Publish(context.Background(), "t", event("e", 5))
+    }()
+    time.Sleep(10 * time.Millisecond)
+    s.Close()
+    assert.Nil(t, <-done, "Got error")
+}
+
+func TestClose(t *testing.T) {
+    b := New()
+    s1 := b.Subscribe("t", nil)
+    s2 := b.Subscribe("t", nil)
+    ctx := context.Background()
+    assert.Nil(t, b.Publish(ctx, "t", event("e", 1)), "Got error")
+
+    s1.Close()
+    s1.Close()
+    assert.Equal(t, 1, b.Subscribers("t"), "Wrong number of subscribers")
+    msg, ok := <-s1.C()
+    assert.True(t, ok, "Buffered message shall remain")
+    assert.NotNil(t, msg, "Buffered message shall remain")
+    _, ok = <-s1.C()
+    assert.False(t, ok, "Channel shall be closed")
+
+    b.Close()
+    assert.Equal(t, ErrClosed, b.Publish(ctx, "t", event("e", 2)), "Shall be closed")
+    assert.Equal(t, []int64{1}, receive(s2), "Wrong messages")
+    _, ok = <-s2.C()
+    assert.False(t, ok, "Channel shall be closed")
+    s3 := b.Subscribe("t", nil)
+    _, ok = <-s3.C()
+    assert.False(t, ok, "Channel shall be closed")
+}
+
+func TestConcurrent(t *testing.T) {
+    b := New()
+    ctx := context.Background()
+    var wg sync.WaitGroup
+    subs := []*Subscription{}
+    for _, p := range []Policy{Block, DropOldest, DropNewest} {
+        s := b.Subscribe("t", &Options{Buffer: 4, Policy: p})
+        subs = append(subs, s)
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for range s.C() {
+            }
+        }()
+    }
+    var pub sync.WaitGroup
+    for i := 0; i < 4; i++ {
+        pub.Add(1)
+        go func() {
+            defer pub.Done()
+            for j := 0; j < 200; j++ {
+                b.Publish(ctx, "t", event("e", j))
+            }
+        }()
+    }
+    pub.Wait()
+    b.Close()
+    wg.Wait()
+    assert.Equal(t, uint64(0), subs[0].Dropped(), "Block shall not drop")
+}
@@ -0,0 +1,235 @@
+// Package cas is a content-addressed store of Binson objects.
+//
+// Objects are stored on disk under the SHA-256 hash of their canonical
+// encoding. Since the encoding of a Binson object is unique, equal objects
+// get equal hashes. An object can refer to other stored objects with
+// reference fields created by Ref, and GC removes every object that can not
+// be reached from a set of roots.
+package cas
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+
+    "github.com/hakanols/binson-go"
+)
+
+// refField is the single field name of a reference object.
+const refField = "cas"
+
+var (
+    // ErrNotFound is returned when an object is not in the store.
+    ErrNotFound = errors.New("Object not found")
+    // ErrCorrupt is returned when stored content does not match its hash.
+    ErrCorrupt = errors.New("Object corrupt")
+)
+
+// Hash is the SHA-256 hash of the canonical bytes of a Binson object.
+type Hash [sha256.Size]byte
+
+// Returns the hash of a Binson object.
+func Sum(b binson.Binson) Hash {
+    return sha256.Sum256(b.ToBytes())
+}
+
+// Returns the hash in hex.
+func (h Hash) String() string {
+    return hex.EncodeToString(h[:])
+}
+
+// Parses a hash in hex.
+func ParseHash(s string) (Hash, error) {
+    var h Hash
+    data, err := hex.DecodeString(s)
+    if err != nil {
+        return h, err
+    }
+    if len(data) != len(h) {
+        return h, fmt.Errorf("Wrong hash length: %d", len(data))
+    }
+    copy(h[:], data)
+    return h, nil
+}
+
+// Returns a reference object {"cas": hash} to put as a field value.
+func Ref(h Hash) binson.Binson {
+    return binson.NewBinson().Put(refField, h[:])
+}
+
+// Returns the hash of a reference object.
+func IsRef(v binson.Value) (Hash, bool) {
+    var h Hash
+    b, ok := v.GetBinson()
+    if !ok || len(b) != 1 {
+        return h, false
+    }
+    data, ok := b.GetBytes(refField)
+    if !ok || len(data) != len(h) {
+        return h, false
+    }
+    copy(h[:], data)
+    return h, true
+}
+
+// Returns the hashes of all reference objects inside b.
+func References(b binson.Binson) []Hash {
+    refs := []Hash{}
+    binson.Walk(b, func(path binson.Path, v binson.Value) error {
+        if h, ok := IsRef(v); ok {
+            refs = append(refs, h)
+            return binson.SkipTree
+        }
+        return nil
+    })
+    return refs
+}
+
+// Store keeps objects as files named by hash in a directory.
+type Store struct {
+    dir string
+}
+
+// Opens the store in dir, creating it if needed.
+func Open(dir string) (*Store, error) {
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return nil, err
+    }
+    return &Store{dir}, nil
+}
+
+func (s *Store) path(h Hash) string {
+    name := h.String()
+    return filepath.Join(s.dir, name[:2], name[2:])
+}
+
+// Stores an object and returns its hash. Storing an object already in the
+// store does nothing.
+func (s *Store) Put(b binson.Binson) (Hash, error) {
+    data := b.ToBytes()
+    h := Hash(sha256.Sum256(data))
+    path := s.path(h)
+    if _, err := os.Stat(path); err == nil {
+        return h, nil
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        return h, err
+    }
+    tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-")
+    if err != nil {
+        return h, err
+    }
+    _, err = tmp.Write(data)
+    if err == nil {
+        err = tmp.Sync()
+    }
+    if closeErr := tmp.Close(); err == nil {
+        err = closeErr
+    }
+    if err == nil {
+        err = os.Rename(tmp.Name(), path)
+    }
+    if err != nil {
+        os.Remove(tmp.Name())
+    }
+    return h, err
+}
+
+// Returns the object with the given hash. The content is verified against
+// the hash.
+func (s *Store) Get(h Hash) (binson.Binson, error) {
+    data, err := os.ReadFile(s.path(h))
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, fmt.Errorf("%w: %s", ErrNotFound, h)
+    }
+    if err != nil {
+        return nil, err
+    }
+    if Hash(sha256.Sum256(data)) != h {
+        return nil, fmt.Errorf("%w: %s", ErrCorrupt, h)
+    }
+    return binson.Parse(data)
+}
+
+// Returns true if an object with the given hash is stored.
+func (s *Store) Has(h Hash) bool {
+    _, err := os.Stat(s.path(h))
+    return err == nil
+}
+
+// Removes the object with the given hash.
+func (s *Store) Delete(h Hash) error {
+    err := os.Remove(s.path(h))
+    if errors.Is(err, os.ErrNotExist) {
+        return fmt.Errorf("%w: %s", ErrNotFound, h)
+    }
+    return err
+}
+
+// Returns the hashes of all stored objects.
+func (s *Store) List() ([]Hash, error) {
+    hashes := []Hash{}
+    dirs, err := os.ReadDir(s.dir)
+    if err != nil {
+        return nil, err
+    }
+    for _, dir := range dirs {
+        if !dir.IsDir() || len(dir.Name()) != 2 {
+            continue
+        }
+        files, err := os.ReadDir(filepath.Join(s.dir, dir.Name()))
+        if err != nil {
+            return nil, err
+        }
+        for _, file := range files {
+            h, err := ParseHash(dir.Name() + file.Name())
+            if err != nil {
+                continue
+            }
+            hashes = append(hashes, h)
+        }
+    }
+    return hashes, nil
+}
+
+// Removes all objects that can not be reached from roots by following
+// references, and returns the number removed. Missing objects are skipped.
+// Objects stored while GC runs may be removed unless they are reachable.
+func (s *Store) GC(roots []Hash) (int, error) {
+    live := make(map[Hash]bool)
+    pending := append([]Hash{}, roots...)
+    for len(pending) > 0 {
+        h := pending[len(pending)-1]
+        pending = pending[:len(pending)-1]
+        if live[h] {
+            continue
+        }
+        live[h] = true
+        b, err := s.Get(h)
+        if errors.Is(err, ErrNotFound) {
+            continue
+        }
+        if err != nil {
+            return 0, err
+        }
+        pending = append(pending, References(b)...)
+    }
+    hashes, err := s.List()
+    if err != nil {
+        return 0, err
+    }
+    removed := 0
+    for _, h := range hashes {
+        if live[h] {
+            continue
+        }
+        if err := s.Delete(h); err != nil {
+            return removed, err
+        }
+        removed++
+    }
+    return removed, nil
+}
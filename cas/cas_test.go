@@ -0,0 +1,84 @@
+package cas
+
+import (
+    "errors"
+    "os"
+    "testing"
+
+    "github.com/hakanols/binson-go"
+    "github.com/stretchr/testify/assert"
+)
+
+func TestPutGet(t *testing.T) {
+    s, err := Open(t.TempDir())
+    assert.Nil(t, err, "Got error")
+    b := binson.NewBinson().
+        Put("a", 1).
+        Put("b", "text")
+    h, err := s.Put(b)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, Sum(b), h, "Wrong hash")
+    again, err := s.Put(binson.NewBinson().Put("b", "text").Put("a", 1))
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, h, again, "Equal objects shall have equal hashes")
+    assert.True(t, s.Has(h), "Should have object")
+
+    got, err := s.Get(h)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, b.ToBytes(), got.ToBytes(), "Bytes do not match")
+
+    parsed, err := ParseHash(h.String())
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, h, parsed, "Wrong hash")
+    _, err = ParseHash("abcd")
+    assert.NotNil(t, err, "Should get error")
+    _, err = ParseHash("xyz")
+    assert.NotNil(t, err, "Should get error")
+
+    os.WriteFile(s.path(h), binson.NewBinson().ToBytes(), 0644)
+    _, err = s.Get(h)
+    assert.True(t, errors.Is(err, ErrCorrupt), "Should get error")
+    assert.Nil(t, s.Delete(h), "Got error")
+    _, err = s.Get(h)
+    assert.True(t, errors.Is(err, ErrNotFound), "Should get error")
+    assert.True(t, errors.Is(s.Delete(h), ErrNotFound), "Should get error")
+}
+
+func TestReferences(t *testing.T) {
+    var h Hash
+    h[0] = 1
+    b := binson.NewBinson().
+        Put("a", Ref(h)).
+        Put("b", binson.NewBinsonArray().
+            Put(Ref(Hash{2})).
+            Put(binson.NewBinson().Put("cas", []byte{1}))).
+        Put("c", binson.NewBinson().Put("cas", h[:]).Put("x", 1))
+    assert.Equal(t, []Hash{h, {2}}, References(b), "References do not match")
+}
+
+func TestGC(t *testing.T) {
+    s, _ := Open(t.TempDir())
+    leaf, _ := s.Put(binson.NewBinson().Put("leaf", 1))
+    shared, _ := s.Put(binson.NewBinson().Put("shared", 1))
+    node, _ := s.Put(binson.NewBinson().
+        Put("left", Ref(leaf)).
+        Put("right", Ref(shared)))
+    root, _ := s.Put(binson.NewBinson().
+        Put("node", Ref(node)).
+        Put("missing", Ref(Hash{9})))
+    orphan, _ := s.Put(binson.NewBinson().Put("orphan", Ref(shared)))
+    garbage, _ := s.Put(binson.NewBinson().Put("garbage", 1))
+
+    removed, err := s.GC([]Hash{root})
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, 2, removed, "Wrong count")
+    for _, h := range []Hash{leaf, shared, node, root} {
+        assert.True(t, s.Has(h), "Should have object")
+    }
+    assert.False(t, s.Has(orphan), "Should be removed")
+    assert.False(t, s.Has(garbage), "Should be removed")
+
+    hashes, err := s.List()
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, 4, len(hashes), "Wrong count")
+}
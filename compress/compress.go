@@ -0,0 +1,232 @@
+// Package compress wraps large Binson objects in compressed envelopes.
+//
+// A compressed envelope is a Binson object
+//
+//     {"z": algorithm, "d": compressed canonical bytes}
+//
+// using compression from the standard library. Decompression is limited
+// in size to protect against decompression bombs.
+package compress
+
+import (
+    "bytes"
+    "compress/flate"
+    "compress/gzip"
+    "compress/zlib"
+    "errors"
+    "fmt"
+    "io"
+    "math"
+
+    "github.com/hakanols/binson-go"
+)
+
+// Algorithm names a compression format.
+type Algorithm string
+
+const (
+    Deflate Algorithm = "deflate"
+    Gzip Algorithm = "gzip"
+    Zlib Algorithm = "zlib"
+)
+
+// DefaultMaxSize is the default limit of decompressed bytes.
+const DefaultMaxSize = 64 * 1024 * 1024
+
+var (
+    // ErrAlgorithm is returned for unsupported algorithms.
+    ErrAlgorithm = errors.New("Unsupported compression")
+    // ErrTooLarge is returned when decompressed data exceeds the limit.
+    ErrTooLarge = errors.New("Decompressed data too large")
+)
+
+func newWriter(w io.Writer, alg Algorithm) (io.WriteCloser, error) {
+    switch alg {
+        case Deflate:
+            return flate.NewWriter(w, flate.DefaultCompression)
+        case Gzip:
+            return gzip.NewWriter(w), nil
+        case Zlib:
+            return zlib.NewWriter(w), nil
+        default:
+            return nil, fmt.Errorf("%w: %s", ErrAlgorithm, alg)
+    }
+}
+
+func newReader(r io.Reader, alg Algorithm) (io.ReadCloser, error) {
+    switch alg {
+        case Deflate:
+            return flate.NewReader(r), nil
+        case Gzip:
+            return gzip.NewReader(r)
+        case Zlib:
+            return zlib.NewReader(r)
+        default:
+            return nil, fmt.Errorf("%w: %s", ErrAlgorithm, alg)
+    }
+}
+
+// Returns b in a deflate envelope if its encoded size is at least
+// threshold bytes, otherwise b itself. See CompressWith.
+func Compress(b binson.Binson, threshold int) (binson.Binson, error) {
+    return CompressWith(b, Deflate, threshold)
+}
+
+// Returns b in an envelope compressed with alg if its encoded size is at
+// least threshold bytes and compression makes it smaller, otherwise b
+// itself.
+func CompressWith(b binson.Binson, alg Algorithm, threshold int) (binson.Binson, error) {
+    size := b.EncodedSize()
+    if size < threshold {
+        return b, nil
+    }
+    var buf bytes.Buffer
+    w, err := newWriter(&buf, alg)
+    if err != nil {
+        return nil, err
+    }
+    if _, err := b.WriteTo(w); err != nil {
+        return nil, err
+    }
+    if err := w.Close(); err != nil {
+        return nil, err
+    }
+    env := binson.NewBinson().
+        Put("z", string(alg)).
+        Put("d", buf.Bytes())
+    if size <= env.EncodedSize() {
+        return b, nil
+    }
+    return env, nil
+}
+
+// Returns true if b is a compressed envelope.
+func IsCompressed(b binson.Binson) bool {
+    return len(b) == 2 && b.HasString("z") && b.HasBytes("d")
+}
+
+// Returns the object in a compressed envelope, limited to DefaultMaxSize
+// bytes. Objects that are not envelopes are returned as they are.
+func Decompress(b binson.Binson) (binson.Binson, error) {
+    return DecompressLimit(b, DefaultMaxSize)
+}
+
+// Returns the object in a compressed envelope, or ErrTooLarge if it is
+// larger than maxSize bytes. Objects that are not envelopes are returned
+// as they are.
+func DecompressLimit(b binson.Binson, maxSize int64) (binson.Binson, error) {
+    if maxSize < 0 {
+        return nil, sizeError(maxSize)
+    }
+    if !IsCompressed(b) {
+        return b, nil
+    }
+    alg, _ := b.GetString("z")
+    data, _ := b.GetBytes("d")
+    r, err := newReader(bytes.NewReader(data), Algorithm(alg))
+    if err != nil {
+        return nil, err
+    }
+    defer r.Close()
+    plain, err := io.ReadAll(io.LimitReader(r, overLimit(maxSize)))
+    if err != nil {
+        return nil, err
+    }
+    if int64(len(plain)) > maxSize {
+        return nil, ErrTooLarge
+    }
+    return binson.Parse(plain)
+}
+
+// Writer compresses a stream of Binson objects written back to back.
+type Writer struct {
+    w io.WriteCloser
+}
+
+// Returns a Writer compressing to w with alg. Close must be called to
+// flush the stream.
+func NewWriter(w io.Writer, alg Algorithm) (*Writer, error) {
+    zw, err := newWriter(w, alg)
+    if err != nil {
+        return nil, err
+    }
+    return &Writer{zw}, nil
+}
+
+// Writes an object to the stream.
+func (w *Writer) Write(b binson.Binson) error {
+    _, err := b.WriteTo(w.w)
+    return err
+}
+
+// Flushes and ends the compressed stream. The underlying writer is not
+// closed.
+func (w *Writer) Close() error {
+    return w.w.Close()
+}
+
+func sizeError(maxSize int64) error {
+    return fmt.Errorf("Negative size limit: %d", maxSize)
+}
+
+// Returns the number of bytes to read to tell if data is larger than
+// maxSize, that is maxSize + 1 without overflowing.
+func overLimit(maxSize int64) int64 {
+    if maxSize == math.MaxInt64 {
+        return maxSize
+    }
+    return maxSize + 1
+}
+
+// limitedReader fails with ErrTooLarge instead of ending at the limit.
+type limitedReader struct {
+    r io.Reader
+    left int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+    if max := overLimit(l.left); int64(len(p)) > max {
+        p = p[:max]
+    }
+    n, err := l.r.Read(p)
+    l.left -= int64(n)
+    if l.left < 0 {
+        return 0, ErrTooLarge
+    }
+    return n, err
+}
+
+// Reader reads a stream of Binson objects written by a Writer.
+type Reader struct {
+    r io.ReadCloser
+    s *binson.Scanner
+}
+
+// Returns a Reader decompressing r with alg. The total decompressed size is
+// limited to maxSize bytes.
+func NewReader(r io.Reader, alg Algorithm, maxSize int64) (*Reader, error) {
+    if maxSize < 0 {
+        return nil, sizeError(maxSize)
+    }
+    zr, err := newReader(r, alg)
+    if err != nil {
+        return nil, err
+    }
+    return &Reader{zr, binson.NewScanner(&limitedReader{zr, maxSize})}, nil
+}
+
+// Returns the next object, or io.EOF at the end of the stream.
+func (r *Reader) Read() (binson.Binson, error) {
+    if r.s.Scan() {
+        return r.s.Binson(), nil
+    }
+    if err := r.s.Err(); err != nil {
+        return nil, err
+    }
+    return nil, io.EOF
+}
+
+// Closes the decompressor. The underlying reader is not closed.
+func (r *Reader) Close() error {
+    return r.r.Close()
+}
@@ -0,0 +1,112 @@
+package compress
+
+import (
+    "bytes"
+    "errors"
+    "io"
+    "math"
+    "testing"
+
+    "github.com/hakanols/binson-go"
+    "github.com/stretchr/testify/assert"
+)
+
+func large() binson.Binson {
+    return binson.NewBinson().
+        Put("name", "report").
+        Put("data", bytes.Repeat([]byte("abcdefgh"), 1000))
+}
+
+func TestCompress(t *testing.T) {
+    for _, alg := range []Algorithm{Deflate, Gzip, Zlib} {
+        b := large()
+        env, err := CompressWith(b, alg, 100)
+        assert.Nil(t, err, "Got error")
+        assert.True(t, IsCompressed(env), "Should be compressed")
+        assert.True(t, env.EncodedSize() < b.EncodedSize() / 10, "Should be smaller")
+        z, _ := env.GetString("z")
+        assert.Equal(t, string(alg), z, "Wrong algorithm")
+
+        back, err := Decompress(env)
+        assert.Nil(t, err, "Got error")
+        assert.Equal(t, b.ToBytes(), back.ToBytes(), "Bytes do not match")
+    }
+
+    small := binson.NewBinson().Put("a", 1)
+    env, err := Compress(small, 100)
+    assert.Nil(t, err, "Got error")
+    assert.False(t, IsCompressed(env), "Small object shall not be compressed")
+    env, err = Compress(binson.NewBinson().Put("a", []byte{1, 9, 3, 7}), 0)
+    assert.Nil(t, err, "Got error")
+    assert.False(t, IsCompressed(env), "Object shall not grow")
+    back, err := Decompress(small)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, small, back, "Object shall be returned as it is")
+
+    _, err = CompressWith(large(), "lzma", 0)
+    assert.True(t, errors.Is(err, ErrAlgorithm), "Should get error")
+    _, err = Decompress(binson.NewBinson().Put("z", "lzma").Put("d", []byte{1}))
+    assert.True(t, errors.Is(err, ErrAlgorithm), "Should get error")
+    _, err = Decompress(binson.NewBinson().Put("z", "gzip").Put("d", []byte{1}))
+    assert.NotNil(t, err, "Should get error")
+}
+
+func TestDecompressLimit(t *testing.T) {
+    bomb, _ := Compress(binson.NewBinson().Put("d", make([]byte, 1000000)), 0)
+    assert.True(t, bomb.EncodedSize() < 2000, "Should compress well")
+    _, err := DecompressLimit(bomb, 10000)
+    assert.Equal(t, ErrTooLarge, err, "Should get error")
+    _, err = DecompressLimit(bomb, 2000000)
+    assert.Nil(t, err, "Got error")
+    _, err = DecompressLimit(bomb, math.MaxInt64)
+    assert.Nil(t, err, "Got error")
+    _, err = DecompressLimit(bomb, -1)
+    assert.NotNil(t, err, "Should get error")
+}
+
+func TestStream(t *testing.T) {
+    var buf bytes.Buffer
+    w, err := NewWriter(&buf, Gzip)
+    assert.Nil(t, err, "Got error")
+    for i := 0; i < 10; i++ {
+        assert.Nil(t, w.Write(large().Put("i", i)), "Got error")
+    }
+    assert.Nil(t, w.Close(), "Got error")
+    assert.True(t, buf.Len() < large().EncodedSize(), "Should compress well")
+
+    r, err := NewReader(bytes.NewReader(buf.Bytes()), Gzip, DefaultMaxSize)
+    assert.Nil(t, err, "Got error")
+    for i := 0; i < 10; i++ {
+        b, err := r.Read()
+        assert.Nil(t, err, "Got error")
+        n, _ := b.GetInt("i")
+        assert.Equal(t, int64(i), n, "Wrong value")
+    }
+    _, err = r.Read()
+    assert.Equal(t, io.EOF, err, "Should end")
+    assert.Nil(t, r.Close(), "Got error")
+
+    r, err = NewReader(bytes.NewReader(buf.Bytes()), Gzip, math.MaxInt64)
+    assert.Nil(t, err, "Got error")
+    _, err = r.Read()
+    assert.Nil(t, err, "Got error")
+    _, err = NewReader(bytes.NewReader(buf.Bytes()), Gzip, -1)
+    assert.NotNil(t, err, "Should get error")
+
+    r, _ = NewReader(bytes.NewReader(buf.Bytes()), Gzip, 20000)
+    count := 0
+    for {
+        _, err = r.Read()
+        if err != nil {
+            break
+        }
+        count++
+    }
+    assert.True(t, errors.Is(err, ErrTooLarge), "Should get error")
+    assert.True(t, count < 3, "Should stop at limit")
+
+    _, err = NewWriter(&buf, "lzma")
+    assert.True(t, errors.Is(err, ErrAlgorithm), "Should get error")
+    _, err = NewReader(&buf, "lzma", 0)
+    assert.True(t, errors.Is(err, ErrAlgorithm), "Should get error")
+}
@@ -0,0 +1,82 @@
+package binson
+
+import (
+    "fmt"
+)
+
+// Converts generic Go data to a Binson object. The value must be a
+// map[string]interface{}; nested maps and []interface{} become Binson
+// objects and arrays, other values are handled as by Put.
+func FromValue(value interface{}) (Binson, error) {
+    m, ok := value.(map[string]interface{})
+    if !ok {
+        return nil, fmt.Errorf("Got none map type: %T", value)
+    }
+    return fromMap(m)
+}
+
+func fromMap(m map[string]interface{}) (Binson, error) {
+    b := NewBinson()
+    for name, value := range m {
+        f, err := fromGo(value)
+        if err != nil {
+            return nil, fmt.Errorf("Field %s: %w", name, err)
+        }
+        b[binsonString(name)] = f
+    }
+    return b, nil
+}
+
+func fromSlice(s []interface{}) (*BinsonArray, error) {
+    a := NewBinsonArray()
+    for i, value := range s {
+        f, err := fromGo(value)
+        if err != nil {
+            return nil, fmt.Errorf("Index %d: %w", i, err)
+        }
+        a.addField(f)
+    }
+    return a, nil
+}
+
+func fromGo(value interface{}) (field, error) {
+    switch o := value.(type) {
+        case map[string]interface{}:
+            return fromMap(o)
+        case []interface{}:
+            return fromSlice(o)
+        default:
+            return toField(o)
+    }
+}
+
+// Converts this Binson object to generic Go data. Nested objects become
+// map[string]interface{} and arrays []interface{}; other values are int64,
+// string, []byte, bool or float64.
+func (b Binson) ToMap() map[string]interface{} {
+    m := make(map[string]interface{}, len(b))
+    for name, f := range b {
+        m[string(name)] = toGo(f)
+    }
+    return m
+}
+
+// Converts this array to generic Go data in the same way as ToMap.
+func (a *BinsonArray) ToSlice() []interface{} {
+    s := make([]interface{}, 0, a.Size())
+    for _, f := range *a {
+        s = append(s, toGo(f))
+    }
+    return s
+}
+
+func toGo(f field) interface{} {
+    switch o := f.(type) {
+        case Binson:
+            return o.ToMap()
+        case *BinsonArray:
+            return o.ToSlice()
+        default:
+            return fieldValue(o)
+    }
+}
@@ -0,0 +1,102 @@
+package binson
+
+import (
+    "fmt"
+    "sync"
+)
+
+// Decoder parses Binson objects while reusing the storage of earlier
+// results. A Decoder is not safe for concurrent use, but can be kept in a
+// sync.Pool.
+type Decoder struct {
+    objects []Binson
+    arrays []*BinsonArray
+}
+
+// Returns a new Decoder.
+func NewDecoder() *Decoder {
+    return &Decoder{}
+}
+
+var decoderPool = sync.Pool{
+    New: func() interface{} { return NewDecoder() },
+}
+
+// Returns an empty Binson object, reused if possible. A nil Decoder always
+// allocates.
+func (dec *Decoder) newBinson() Binson {
+    if dec == nil || len(dec.objects) == 0 {
+        return NewBinson()
+    }
+    b := dec.objects[len(dec.objects)-1]
+    dec.objects[len(dec.objects)-1] = nil
+    dec.objects = dec.objects[:len(dec.objects)-1]
+    return b
+}
+
+// Returns an empty array, reused if possible. A nil Decoder always
+// allocates.
+func (dec *Decoder) newArray() *BinsonArray {
+    if dec == nil || len(dec.arrays) == 0 {
+        return NewBinsonArray()
+    }
+    a := dec.arrays[len(dec.arrays)-1]
+    dec.arrays[len(dec.arrays)-1] = nil
+    dec.arrays = dec.arrays[:len(dec.arrays)-1]
+    return a
+}
+
+// Empties the nested objects and arrays of f and keeps them for reuse.
+// Empty ones are skipped, so an object that occurs twice is only kept once.
+func (dec *Decoder) recycle(f field) {
+    switch o := f.(type) {
+        case Binson:
+            if len(o) == 0 {
+                return
+            }
+            for _, v := range o {
+                dec.recycle(v)
+            }
+            o.Reset()
+            dec.objects = append(dec.objects, o)
+        case *BinsonArray:
+            if o.Size() == 0 {
+                return
+            }
+            for _, v := range *o {
+                dec.recycle(v)
+            }
+            o.Reset()
+            dec.arrays = append(dec.arrays, o)
+    }
+}
+
+// Parses bytes into dst, replacing all its fields. The storage of dst and
+// of its nested objects and arrays is reused, so values previously taken
+// from dst must not be used afterwards. On error dst holds a partial result.
+func (dec *Decoder) ParseInto(dst Binson, data []byte) error {
+    if dst == nil {
+        return fmt.Errorf("Can not parse into nil Binson")
+    }
+    for _, v := range dst {
+        dec.recycle(v)
+    }
+    dst.Reset()
+    d := decoder{data: data, free: dec}
+    start, err := d.readByte()
+    if err != nil {
+        return err
+    }
+    if start != binsonBegin {
+        return fmt.Errorf("Got none Binson start: %X", start)
+    }
+    return d.parseBinsonInto(dst)
+}
+
+// Parses bytes into dst, replacing all its fields. The storage of dst and
+// of its nested objects and arrays is reused, see Decoder.ParseInto.
+func ParseInto(dst Binson, data []byte) error {
+    dec := decoderPool.Get().(*Decoder)
+    defer decoderPool.Put(dec)
+    return dec.ParseInto(dst, data)
+}
@@ -0,0 +1,176 @@
+// Package envelope defines a standard wrapper for Binson messages.
+//
+// An envelope is a Binson object
+//
+//     {"type": string, "version": int, "id": string, "ts": int,
+//      "headers": object, "body": object}
+//
+// where ts is the creation time in milliseconds since the Unix epoch. The
+// package has constructors, validation, dispatch of envelopes to handlers
+// registered by type and version, and helpers for carrying W3C trace
+// context in the traceparent and tracestate headers.
+package envelope
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "sync"
+    "time"
+
+    "github.com/hakanols/binson-go"
+)
+
+var (
+    // ErrInvalid is returned for envelopes missing required fields.
+    ErrInvalid = errors.New("Invalid envelope")
+    // ErrNoHandler is returned when no handler is registered for a type.
+    ErrNoHandler = errors.New("No handler")
+)
+
+// Envelope is a message with type, version, id, time and headers around a
+// Binson body.
+type Envelope struct {
+    Type string
+    Version int64
+    ID string
+    Time time.Time
+    Headers binson.Binson
+    Body binson.Binson
+}
+
+// Returns a random id of 16 bytes in hex.
+func NewID() string {
+    id := make([]byte, 16)
+    rand.Read(id)
+    return hex.EncodeToString(id)
+}
+
+// Returns a new envelope with a random id and the current time.
+func New(typ string, version int64, body binson.Binson) *Envelope {
+    return &Envelope{
+        Type: typ,
+        Version: version,
+        ID: NewID(),
+        Time: time.Now(),
+        Headers: binson.NewBinson(),
+        Body: body,
+    }
+}
+
+// Returns an error if a required field is missing or invalid.
+func (e *Envelope) Validate() error {
+    switch {
+        case e.Type == "":
+            return fmt.Errorf("%w: missing type", ErrInvalid)
+        case e.Version < 1:
+            return fmt.Errorf("%w: version %d", ErrInvalid, e.Version)
+        case e.ID == "":
+            return fmt.Errorf("%w: missing id", ErrInvalid)
+        case e.Time.IsZero():
+            return fmt.Errorf("%w: missing ts", ErrInvalid)
+        case e.Body == nil:
+            return fmt.Errorf("%w: missing body", ErrInvalid)
+    }
+    if tp, ok := e.Headers.GetString(traceparentHeader); ok {
+        if _, err := ParseTraceparent(tp); err != nil {
+            return fmt.Errorf("%w: %v", ErrInvalid, err)
+        }
+    }
+    return nil
+}
+
+// Returns the envelope as a Binson object.
+func (e *Envelope) ToBinson() binson.Binson {
+    headers := e.Headers
+    if headers == nil {
+        headers = binson.NewBinson()
+    }
+    return binson.NewBinson().
+        Put("type", e.Type).
+        Put("version", e.Version).
+        Put("id", e.ID).
+        Put("ts", e.Time.UnixMilli()).
+        Put("headers", headers).
+        Put("body", e.Body)
+}
+
+// Returns the envelope in a Binson object after validating it. A missing
+// headers object is treated as empty.
+func FromBinson(b binson.Binson) (*Envelope, error) {
+    e := &Envelope{}
+    e.Type, _ = b.GetString("type")
+    e.Version, _ = b.GetInt("version")
+    e.ID, _ = b.GetString("id")
+    if ts, ok := b.GetInt("ts"); ok {
+        e.Time = time.UnixMilli(ts)
+    }
+    e.Headers, _ = b.GetBinson("headers")
+    if e.Headers == nil {
+        if b.ContainsKey("headers") {
+            return nil, fmt.Errorf("%w: headers is not an object", ErrInvalid)
+        }
+        e.Headers = binson.NewBinson()
+    }
+    e.Body, _ = b.GetBinson("body")
+    if err := e.Validate(); err != nil {
+        return nil, err
+    }
+    return e, nil
+}
+
+// Parses bytes to an envelope, see FromBinson.
+func Parse(data []byte) (*Envelope, error) {
+    b, err := binson.Parse(data)
+    if err != nil {
+        return nil, err
+    }
+    return FromBinson(b)
+}
+
+// Handler handles envelopes of one type.
+type Handler func(ctx context.Context, e *Envelope) error
+
+type route struct {
+    typ string
+    version int64
+}
+
+// Router dispatches envelopes to handlers by type and version. It is safe
+// for concurrent use.
+type Router struct {
+    mu sync.RWMutex
+    handlers map[route]Handler
+}
+
+// Returns a new Router without handlers.
+func NewRouter() *Router {
+    return &Router{handlers: make(map[route]Handler)}
+}
+
+// Registers h for envelopes of the given type and version. Version 0
+// registers h for all versions without a handler of their own.
+func (r *Router) Handle(typ string, version int64, h Handler) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.handlers[route{typ, version}] = h
+}
+
+// Validates e and calls the handler registered for its type and version.
+func (r *Router) Dispatch(ctx context.Context, e *Envelope) error {
+    if err := e.Validate(); err != nil {
+        return err
+    }
+    r.mu.RLock()
+    h, ok := r.handlers[route{e.Type, e.Version}]
+    if !ok {
+        h, ok = r.handlers[route{e.Type, 0}]
+    }
+    r.mu.RUnlock()
+    if !ok {
+        return fmt.Errorf("%w: %s version %d", ErrNoHandler, e.Type, e.Version)
+    }
+    return h(ctx, e)
+}
@@ -0,0 +1,129 @@
+package envelope
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "github.com/hakanols/binson-go"
+    "github.com/stretchr/testify/assert"
+)
+
+func TestRoundTrip(t *testing.T) {
+    e := New("order.created", 2, binson.NewBinson().Put("order", 17))
+    e.Headers.Put("tenant", "acme")
+    assert.Nil(t, e.Validate(), "Got error")
+    assert.Equal(t, 32, len(e.ID), "Wrong id length")
+
+    back, err := Parse(e.ToBinson().ToBytes())
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, e.Type, back.Type, "Wrong type")
+    assert.Equal(t, e.Version, back.Version, "Wrong version")
+    assert.Equal(t, e.ID, back.ID, "Wrong id")
+    assert.Equal(t, e.Time.UnixMilli(), back.Time.UnixMilli(), "Wrong time")
+    assert.Equal(t, e.Headers.ToBytes(), back.Headers.ToBytes(), "Headers do not match")
+    assert.Equal(t, e.Body.ToBytes(), back.Body.ToBytes(), "Body do not match")
+
+    noHeaders := e.ToBinson()
+    noHeaders.Remove("headers")
+    back, err = FromBinson(noHeaders)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, binson.NewBinson(), back.Headers, "Headers shall be empty")
+}
+
+func TestValidate(t *testing.T) {
+    valid := func() binson.Binson {
+        return New("a", 1, binson.NewBinson()).ToBinson()
+    }
+    for _, change := range []func(b binson.Binson){
+        func(b binson.Binson) { b.Remove("type") },
+        func(b binson.Binson) { b.Put("version", 0) },
+        func(b binson.Binson) { b.Put("id", "") },
+        func(b binson.Binson) { b.Remove("ts") },
+        func(b binson.Binson) { b.Put("body", "text") },
+        func(b binson.Binson) { b.Put("headers", 1) },
+        func(b binson.Binson) { b.Put("headers", binson.NewBinson().Put("traceparent", "bad")) },
+    } {
+        b := valid()
+        change(b)
+        _, err := FromBinson(b)
+        assert.True(t, errors.Is(err, ErrInvalid), "Should get error for %v", b)
+    }
+    _, err := Parse([]byte{0x41})
+    assert.NotNil(t, err, "Should get error")
+    e := &Envelope{Type: "a", Version: 1, ID: "x", Time: time.Now(), Body: binson.NewBinson()}
+    assert.Nil(t, e.Validate(), "Got error")
+    assert.True(t, e.ToBinson().HasBinson("headers"), "Should have headers")
+}
+
+func TestRouter(t *testing.T) {
+    r := NewRouter()
+    got := []string{}
+    r.Handle("ping", 1, func(ctx context.Context, e *Envelope) error {
+        got = append(got, "ping v1")
+        return nil
+    })
+    r.Handle("ping", 0, func(ctx context.Context, e *Envelope) error {
+        got = append(got, "ping any")
+        return nil
+    })
+    failure := errors.New("failed")
+    r.Handle("fail", 1, func(ctx context.Context, e *Envelope) error {
+        return failure
+    })
+    ctx := context.Background()
+    assert.Nil(t, r.Dispatch(ctx, New("ping", 1, binson.NewBinson())), "Got error")
+    assert.Nil(t, r.Dispatch(ctx, New("ping", 3, binson.NewBinson())), "Got error")
+    assert.Equal(t, []string{"ping v1", "ping any"}, got, "Wrong handlers")
+    assert.Equal(t, failure, r.Dispatch(ctx, New("fail", 1, binson.NewBinson())), "Should get error")
+    err := r.Dispatch(ctx, New("other", 1, binson.NewBinson()))
+    assert.True(t, errors.Is(err, ErrNoHandler), "Should get error")
+    err = r.Dispatch(ctx, New("ping", 1, nil))
+    assert.True(t, errors.Is(err, ErrInvalid), "Should get error")
+}
+
+func TestTraceContext(t *testing.T) {
+    s := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+    tc, err := ParseTraceparent(s)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, s, tc.String(), "Wrong traceparent")
+    assert.True(t, tc.Sampled(), "Should be sampled")
+    _, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+    assert.Nil(t, err, "Later versions shall be accepted")
+
+    for _, bad := range []string{
+        "",
+        "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+        "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+        "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+        "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+        "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+        "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+        "00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+    } {
+        _, err = ParseTraceparent(bad)
+        assert.NotNil(t, err, "Should get error for %q", bad)
+    }
+
+    e := New("request", 1, binson.NewBinson())
+    _, ok := e.Trace()
+    assert.False(t, ok, "Should not have trace")
+    e.SetTrace(tc)
+    e.Headers.Put("tracestate", "vendor=value")
+    got, ok := e.Trace()
+    assert.True(t, ok, "Should have trace")
+    assert.Equal(t, tc, got, "Wrong trace")
+
+    d := e.Derive("response", 1, binson.NewBinson())
+    child, ok := d.Trace()
+    assert.True(t, ok, "Should have trace")
+    assert.Equal(t, tc.TraceID, child.TraceID, "Shall continue trace")
+    assert.NotEqual(t, tc.ParentID, child.ParentID, "Shall get new parent id")
+    state, _ := d.Headers.GetString("tracestate")
+    assert.Equal(t, "vendor=value", state, "Shall copy tracestate")
+
+    fresh := New("x", 1, binson.NewBinson()).Derive("y", 1, binson.NewBinson())
+    _, ok = fresh.Trace()
+    assert.True(t, ok, "Shall start new trace")
+}
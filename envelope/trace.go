@@ -0,0 +1,120 @@
+package envelope
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "fmt"
+    "strings"
+
+    "github.com/hakanols/binson-go"
+)
+
+const (
+    traceparentHeader = "traceparent"
+    tracestateHeader = "tracestate"
+)
+
+// TraceContext is a W3C trace context as carried in a traceparent header.
+type TraceContext struct {
+    TraceID [16]byte
+    ParentID [8]byte
+    Flags byte
+}
+
+// Returns a new trace context with random ids and the sampled flag set.
+func NewTraceContext() TraceContext {
+    var tc TraceContext
+    rand.Read(tc.TraceID[:])
+    rand.Read(tc.ParentID[:])
+    tc.Flags = 0x01
+    return tc
+}
+
+// Returns a trace context in the same trace with a new random parent id.
+func (tc TraceContext) Child() TraceContext {
+    rand.Read(tc.ParentID[:])
+    return tc
+}
+
+// Returns true if the sampled flag is set.
+func (tc TraceContext) Sampled() bool {
+    return tc.Flags & 0x01 != 0
+}
+
+// Returns the traceparent header value.
+func (tc TraceContext) String() string {
+    return fmt.Sprintf("00-%x-%x-%02x", tc.TraceID, tc.ParentID, tc.Flags)
+}
+
+func decodeLowerHex(dst []byte, s string) bool {
+    if s != strings.ToLower(s) {
+        return false
+    }
+    n, err := hex.Decode(dst, []byte(s))
+    return err == nil && n == len(dst)
+}
+
+// Parses a traceparent header value. Only version 00 is accepted in full;
+// later versions are read by their first four fields.
+func ParseTraceparent(s string) (TraceContext, error) {
+    var tc TraceContext
+    if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+        return tc, fmt.Errorf("Malformed traceparent: %q", s)
+    }
+    var version [1]byte
+    var flags [1]byte
+    if !decodeLowerHex(version[:], s[:2]) || version[0] == 0xff {
+        return tc, fmt.Errorf("Bad traceparent version: %q", s)
+    }
+    if version[0] == 0 && len(s) != 55 {
+        return tc, fmt.Errorf("Malformed traceparent: %q", s)
+    }
+    if version[0] != 0 && len(s) > 55 && s[55] != '-' {
+        return tc, fmt.Errorf("Malformed traceparent: %q", s)
+    }
+    if !decodeLowerHex(tc.TraceID[:], s[3:35]) || tc.TraceID == [16]byte{} {
+        return tc, fmt.Errorf("Bad trace id: %q", s)
+    }
+    if !decodeLowerHex(tc.ParentID[:], s[36:52]) || tc.ParentID == [8]byte{} {
+        return tc, fmt.Errorf("Bad parent id: %q", s)
+    }
+    if !decodeLowerHex(flags[:], s[53:55]) {
+        return tc, fmt.Errorf("Bad trace flags: %q", s)
+    }
+    tc.Flags = flags[0]
+    return tc, nil
+}
+
+// Sets the traceparent header.
+func (e *Envelope) SetTrace(tc TraceContext) {
+    if e.Headers == nil {
+        e.Headers = binson.NewBinson()
+    }
+    e.Headers.Put(traceparentHeader, tc.String())
+}
+
+// Returns the trace context of the traceparent header.
+func (e *Envelope) Trace() (TraceContext, bool) {
+    s, ok := e.Headers.GetString(traceparentHeader)
+    if !ok {
+        return TraceContext{}, false
+    }
+    tc, err := ParseTraceparent(s)
+    return tc, err == nil
+}
+
+// Returns a new envelope caused by e. It continues the trace of e with a
+// new parent id, or starts a new trace if e has none, and copies the
+// tracestate header.
+func (e *Envelope) Derive(typ string, version int64, body binson.Binson) *Envelope {
+    d := New(typ, version, body)
+    if tc, ok := e.Trace(); ok {
+        d.SetTrace(tc.Child())
+    } else {
+        d.SetTrace(NewTraceContext())
+    }
+    if state, ok := e.Headers.GetString(tracestateHeader); ok {
+        d.Headers.Put(tracestateHeader, state)
+    }
+    return d
+}
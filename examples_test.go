@@ -3,6 +3,7 @@ package binson_test
 import (
     "fmt"
     "math"
+    "time"
     "github.com/hakanols/binson-go"
 )
 
@@ -119,4 +120,17 @@ func ExampleBinson() {
     // 40140161100114016210FF14016311FA0041
     // HasInt('x'): false
     // GetInt('c'): 250
-}
\ No newline at end of file
+}
+
+type timestamp time.Time
+
+func (t timestamp) MarshalBinson() (interface{}, error) {
+    return time.Time(t).Unix(), nil
+}
+
+func ExampleMarshaler() {
+    b := binson.NewBinson().
+        Put("t", timestamp(time.Unix(1000, 0)))
+    fmt.Printf("%X\n", b.ToBytes())
+    // Output: 4014017411E80341
+}
@@ -0,0 +1,60 @@
+package binson
+
+import (
+    "fmt"
+)
+
+// Type is the set of Go types that Binson values are read as.
+type Type interface {
+    Binson | *BinsonArray | int64 | string | []byte | bool | float64
+}
+
+func fieldAs[T Type](f field, found bool) (T, bool) {
+    var zero T
+    if !found {
+        return zero, false
+    }
+    v, ok := fieldValue(f).(T)
+    return v, ok
+}
+
+// Returns the field with the given name if it is of type T.
+func Get[T Type](b Binson, name string) (T, bool) {
+    f, found := b[binsonString(name)]
+    return fieldAs[T](f, found)
+}
+
+// Returns the element at the given index if it is of type T.
+func At[T Type](a *BinsonArray, index int) (T, bool) {
+    if a.inRange(index){
+        var zero T
+        return zero, false
+    }
+    return fieldAs[T]((*a)[index], true)
+}
+
+// Returns the field with the given name if it is of type T, otherwise def.
+func GetOr[T Type](b Binson, name string, def T) (T) {
+    if v, ok := Get[T](b, name); ok {
+        return v
+    }
+    return def
+}
+
+// Returns the element at the given index if it is of type T, otherwise def.
+func AtOr[T Type](a *BinsonArray, index int, def T) (T) {
+    if v, ok := At[T](a, index); ok {
+        return v
+    }
+    return def
+}
+
+// Returns v and panics if ok is false. Wraps calls to Get and At, as in
+// Must(Get[string](b, "name")).
+func Must[T Type](v T, ok bool) (T) {
+    if !ok {
+        var zero T
+        panic(fmt.Sprintf("Missing value of type %T", zero))
+    }
+    return v
+}
@@ -0,0 +1,138 @@
+package binson
+
+import (
+    "errors"
+    "iter"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// Returns an iterator over the fields of this Binson object in canonical
+// order, yielding each field name and its value.
+func (b Binson) All() iter.Seq2[string, Value] {
+    return func(yield func(string, Value) bool) {
+        keys := make([]string, 0, len(b))
+        for k := range b {
+            keys = append(keys, string(k))
+        }
+        sort.Strings(keys)
+        for _, k := range keys {
+            f, ok := b[binsonString(k)]
+            if !ok {
+                continue
+            }
+            if !yield(k, Value{f}) {
+                return
+            }
+        }
+    }
+}
+
+// Returns an iterator over the elements of this array, yielding each index
+// and its value.
+func (a *BinsonArray) All() iter.Seq2[int, Value] {
+    return func(yield func(int, Value) bool) {
+        for i := 0; i < a.Size(); i++ {
+            if !yield(i, Value{(*a)[i]}) {
+                return
+            }
+        }
+    }
+}
+
+// Path locates a value inside a Binson object or array. Each element is
+// either a field name (string) or an array index (int).
+type Path []interface{}
+
+// Returns the path in the form a.b[2].c.
+func (p Path) String() string {
+    var sb strings.Builder
+    for i, e := range p {
+        switch o := e.(type) {
+            case string:
+                if i > 0 {
+                    sb.WriteByte('.')
+                }
+                sb.WriteString(o)
+            case int:
+                sb.WriteByte('[')
+                sb.WriteString(strconv.Itoa(o))
+                sb.WriteByte(']')
+        }
+    }
+    return sb.String()
+}
+
+// Returns the value at the given path below root. The empty path gives the
+// root itself.
+func Lookup(root Binson, path Path) (Value, bool) {
+    var f field = root
+    for _, e := range path {
+        switch o := e.(type) {
+            case string:
+                b, ok := f.(Binson)
+                if !ok {
+                    return Value{}, false
+                }
+                if f, ok = b[binsonString(o)]; !ok {
+                    return Value{}, false
+                }
+            case int:
+                a, ok := f.(*BinsonArray)
+                if !ok || a.inRange(o) {
+                    return Value{}, false
+                }
+                f = (*a)[o]
+            default:
+                return Value{}, false
+        }
+    }
+    return Value{f}, true
+}
+
+// SkipTree can be returned by a WalkFunc to skip the children of the
+// current Binson object or array.
+var SkipTree = errors.New("Skip this tree")
+
+// WalkFunc is called by Walk for each value with the path to the value.
+type WalkFunc func(path Path, v Value) error
+
+// Walks a Binson object, array or Value recursively in canonical order and
+// calls fn for each value, starting with the root at the empty path. If fn
+// returns SkipTree the children of that value are not visited; any other
+// error stops the walk and is returned by Walk.
+func Walk(root interface{}, fn WalkFunc) error {
+    f, err := toField(root)
+    if err != nil {
+        return err
+    }
+    err = walk(Path{}, f, fn)
+    if err == SkipTree {
+        return nil
+    }
+    return err
+}
+
+func walk(path Path, f field, fn WalkFunc) error {
+    if err := fn(path, Value{f}); err != nil {
+        return err
+    }
+    switch o := f.(type) {
+        case Binson:
+            for name, v := range o.All() {
+                err := walk(append(path[:len(path):len(path)], name), v.f, fn)
+                if err != nil && err != SkipTree {
+                    return err
+                }
+            }
+        case *BinsonArray:
+            for i, v := range o.All() {
+                err := walk(append(path[:len(path):len(path)], i), v.f, fn)
+                if err != nil && err != SkipTree {
+                    return err
+                }
+            }
+    }
+    return nil
+}
@@ -0,0 +1,496 @@
+// Package kv is a single-file key-value store with Binson values.
+//
+// Writes are appended to the file as checksummed batch records and synced
+// before they are applied, so a batch is either fully stored or not at all.
+// The whole store is kept in memory; Compact rewrites the file with only the
+// live entries. Secondary indexes map the value at a field path of each
+// entry to the keys holding it. Indexes are kept in memory only and are
+// declared after opening the store.
+package kv
+
+import (
+    "bufio"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "hash/crc32"
+    "io"
+    "iter"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+
+    "github.com/hakanols/binson-go"
+)
+
+const headerSize = 8
+
+var (
+    // ErrClosed is returned when using a closed store.
+    ErrClosed = errors.New("Store closed")
+    // ErrNoIndex is returned when querying an index that does not exist.
+    ErrNoIndex = errors.New("No such index")
+    // ErrNilValue is returned when writing a nil value.
+    ErrNilValue = errors.New("Nil value")
+    // ErrCorrupt is returned by Open when a complete record fails its
+    // checksum or can not be decoded.
+    ErrCorrupt = errors.New("Corrupt store")
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type op struct {
+    key string
+    value binson.Binson
+}
+
+// Batch is a set of writes applied atomically by DB.Write.
+type Batch struct {
+    ops []op
+    err error
+}
+
+// Returns a new empty Batch.
+func NewBatch() *Batch {
+    return &Batch{}
+}
+
+// Adds setting key to value. A nil value is an error reported by Err and
+// DB.Write, use Delete to remove a key.
+func (b *Batch) Put(key string, value binson.Binson) *Batch {
+    if value == nil {
+        if b.err == nil {
+            b.err = fmt.Errorf("%w: %s", ErrNilValue, key)
+        }
+        return b
+    }
+    b.ops = append(b.ops, op{key, value})
+    return b
+}
+
+// Returns the first error of the batch, or nil.
+func (b *Batch) Err() error {
+    return b.err
+}
+
+// Adds removal of key.
+func (b *Batch) Delete(key string) *Batch {
+    b.ops = append(b.ops, op{key, nil})
+    return b
+}
+
+type index struct {
+    path binson.Path
+    keys map[string]map[string]struct{}
+    byKey map[string]string
+}
+
+func (ix *index) add(key string, value binson.Binson) {
+    v, ok := binson.Lookup(value, ix.path)
+    if !ok {
+        return
+    }
+    indexed := string(v.ToBytes())
+    set, ok := ix.keys[indexed]
+    if !ok {
+        set = make(map[string]struct{})
+        ix.keys[indexed] = set
+    }
+    set[key] = struct{}{}
+    ix.byKey[key] = indexed
+}
+
+func (ix *index) remove(key string) {
+    indexed, ok := ix.byKey[key]
+    if !ok {
+        return
+    }
+    delete(ix.byKey, key)
+    delete(ix.keys[indexed], key)
+    if len(ix.keys[indexed]) == 0 {
+        delete(ix.keys, indexed)
+    }
+}
+
+// DB is a key-value store kept in a single file. It is safe for concurrent
+// use.
+type DB struct {
+    mu sync.RWMutex
+    path string
+    file *os.File
+    size int64
+    entries map[string][]byte
+    indexes map[string]*index
+    closed bool
+}
+
+// Opens the store in the file at path, creating it if needed. A record at
+// the end of the file that is incomplete or bad, left by a crash during a
+// write, is removed. A bad record followed by other data gives ErrCorrupt
+// and the file is left unchanged.
+func Open(path string) (*DB, error) {
+    file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+    if err != nil {
+        return nil, err
+    }
+    db := &DB{
+        path: path,
+        file: file,
+        entries: make(map[string][]byte),
+        indexes: make(map[string]*index),
+    }
+    if err := db.load(); err != nil {
+        file.Close()
+        return nil, err
+    }
+    return db, nil
+}
+
+func (db *DB) load() error {
+    info, err := db.file.Stat()
+    if err != nil {
+        return err
+    }
+    r := bufio.NewReader(db.file)
+    header := make([]byte, headerSize)
+    var end int64
+    for {
+        if _, err := io.ReadFull(r, header); err != nil {
+            break
+        }
+        length := binary.LittleEndian.Uint32(header)
+        if int64(length) > info.Size() - end - headerSize {
+            break
+        }
+        payload := make([]byte, length)
+        if _, err := io.ReadFull(r, payload); err != nil {
+            break
+        }
+        next := end + headerSize + int64(length)
+        var ops []op
+        if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:]) {
+            err = fmt.Errorf("%w: Checksum mismatch at offset %d", ErrCorrupt, end)
+        } else if ops, err = decodeBatch(payload); err != nil {
+            err = fmt.Errorf("%w: Record at offset %d: %v", ErrCorrupt, end, err)
+        }
+        if err != nil {
+            torn, tornErr := tornTail(db.file, end, next, info.Size())
+            if tornErr != nil {
+                return tornErr
+            }
+            if !torn {
+                return err
+            }
+            break
+        }
+        db.apply(ops)
+        end = next
+    }
+    if end != info.Size() {
+        if err := db.file.Truncate(end); err != nil {
+            return err
+        }
+        if err := db.file.Sync(); err != nil {
+            return err
+        }
+    }
+    db.size = end
+    return nil
+}
+
+// Reports whether a bad record from offset to next can be a write torn by
+// a crash, that is the record ends at the end of the file or everything
+// from offset on is zero.
+func tornTail(file *os.File, offset int64, next int64, size int64) (bool, error) {
+    if next == size {
+        return true, nil
+    }
+    r := bufio.NewReader(io.NewSectionReader(file, offset, size - offset))
+    for {
+        c, err := r.ReadByte()
+        if err == io.EOF {
+            return true, nil
+        }
+        if err != nil {
+            return false, err
+        }
+        if c != 0 {
+            return false, nil
+        }
+    }
+}
+
+// Encodes a batch as {"o": [{"k": key, "v": value}, {"k": key}, ...]},
+// where a missing value means removal.
+func encodeBatch(ops []op) []byte {
+    list := binson.NewBinsonArray()
+    for _, o := range ops {
+        entry := binson.NewBinson().Put("k", o.key)
+        if o.value != nil {
+            entry.Put("v", o.value)
+        }
+        list.Put(entry)
+    }
+    payload := binson.NewBinson().Put("o", list).ToBytes()
+    record := make([]byte, headerSize, headerSize + len(payload))
+    binary.LittleEndian.PutUint32(record, uint32(len(payload)))
+    binary.LittleEndian.PutUint32(record[4:], crc32.Checksum(payload, crcTable))
+    return append(record, payload...)
+}
+
+func decodeBatch(payload []byte) ([]op, error) {
+    b, err := binson.Parse(payload)
+    if err != nil {
+        return nil, err
+    }
+    list, ok := b.GetArray("o")
+    if !ok {
+        return nil, fmt.Errorf("Batch without operations")
+    }
+    ops := make([]op, 0, list.Size())
+    for i := 0; i < list.Size(); i++ {
+        entry, ok := list.GetBinson(i)
+        if !ok {
+            return nil, fmt.Errorf("Bad operation at %d", i)
+        }
+        key, ok := entry.GetString("k")
+        if !ok {
+            return nil, fmt.Errorf("Operation without key at %d", i)
+        }
+        value, _ := entry.GetBinson("v")
+        ops = append(ops, op{key, value})
+    }
+    return ops, nil
+}
+
+func (db *DB) apply(ops []op) {
+    for _, o := range ops {
+        for _, ix := range db.indexes {
+            ix.remove(o.key)
+        }
+        if o.value == nil {
+            delete(db.entries, o.key)
+            continue
+        }
+        db.entries[o.key] = o.value.ToBytes()
+        for _, ix := range db.indexes {
+            ix.add(o.key, o.value)
+        }
+    }
+}
+
+// Returns a copy of the value stored under key.
+func (db *DB) Get(key string) (binson.Binson, bool) {
+    db.mu.RLock()
+    data, ok := db.entries[key]
+    db.mu.RUnlock()
+    if !ok {
+        return nil, false
+    }
+    b, err := binson.Parse(data)
+    return b, err == nil
+}
+
+// Returns the number of keys in the store.
+func (db *DB) Len() int {
+    db.mu.RLock()
+    defer db.mu.RUnlock()
+    return len(db.entries)
+}
+
+// Stores value under key.
+func (db *DB) Put(key string, value binson.Binson) error {
+    return db.Write(NewBatch().Put(key, value))
+}
+
+// Removes key from the store.
+func (db *DB) Delete(key string) error {
+    return db.Write(NewBatch().Delete(key))
+}
+
+// Applies all writes of the batch atomically. The batch is synced to disk
+// before Write returns.
+func (db *DB) Write(batch *Batch) error {
+    if batch.err != nil {
+        return batch.err
+    }
+    if len(batch.ops) == 0 {
+        return nil
+    }
+    record := encodeBatch(batch.ops)
+    ops, err := decodeBatch(record[headerSize:])
+    if err != nil {
+        return err
+    }
+    db.mu.Lock()
+    defer db.mu.Unlock()
+    if db.closed {
+        return ErrClosed
+    }
+    if _, err := db.file.WriteAt(record, db.size); err != nil {
+        db.file.Truncate(db.size)
+        return err
+    }
+    if err := db.file.Sync(); err != nil {
+        db.file.Truncate(db.size)
+        return err
+    }
+    db.size += int64(len(record))
+    db.apply(ops)
+    return nil
+}
+
+// Returns an iterator over the keys starting with prefix and copies of
+// their values, in key order. The keys are taken when iteration starts.
+func (db *DB) Scan(prefix string) iter.Seq2[string, binson.Binson] {
+    return func(yield func(string, binson.Binson) bool) {
+        db.mu.RLock()
+        keys := []string{}
+        for key := range db.entries {
+            if strings.HasPrefix(key, prefix) {
+                keys = append(keys, key)
+            }
+        }
+        db.mu.RUnlock()
+        sort.Strings(keys)
+        for _, key := range keys {
+            value, ok := db.Get(key)
+            if !ok {
+                continue
+            }
+            if !yield(key, value) {
+                return
+            }
+        }
+    }
+}
+
+// Declares an index over the value at path of every entry. Entries without
+// a value at path are not indexed. An existing index with the same name is
+// replaced.
+func (db *DB) CreateIndex(name string, path binson.Path) error {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+    if db.closed {
+        return ErrClosed
+    }
+    ix := &index{
+        path: path,
+        keys: make(map[string]map[string]struct{}),
+        byKey: make(map[string]string),
+    }
+    for key, data := range db.entries {
+        value, err := binson.Parse(data)
+        if err != nil {
+            return err
+        }
+        ix.add(key, value)
+    }
+    db.indexes[name] = ix
+    return nil
+}
+
+// Removes an index.
+func (db *DB) DropIndex(name string) {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+    delete(db.indexes, name)
+}
+
+// Returns the keys, in order, whose value at the path of the index equals
+// value. The value is given as for Put on Binson.
+func (db *DB) Find(name string, value interface{}) ([]string, error) {
+    v, err := binson.ValueOf(value)
+    if err != nil {
+        return nil, err
+    }
+    db.mu.RLock()
+    defer db.mu.RUnlock()
+    ix, ok := db.indexes[name]
+    if !ok {
+        return nil, fmt.Errorf("%w: %s", ErrNoIndex, name)
+    }
+    keys := []string{}
+    for key := range ix.keys[string(v.ToBytes())] {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys, nil
+}
+
+// Rewrites the file with only the live entries. The new file replaces the
+// old one atomically.
+func (db *DB) Compact() error {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+    if db.closed {
+        return ErrClosed
+    }
+    tmpPath := db.path + ".compact"
+    tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+    if err != nil {
+        return err
+    }
+    keys := make([]string, 0, len(db.entries))
+    for key := range db.entries {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    w := bufio.NewWriter(tmp)
+    var size int64
+    for _, key := range keys {
+        value, err := binson.Parse(db.entries[key])
+        if err == nil {
+            var n int
+            n, err = w.Write(encodeBatch([]op{{key, value}}))
+            size += int64(n)
+        }
+        if err != nil {
+            tmp.Close()
+            os.Remove(tmpPath)
+            return err
+        }
+    }
+    if err := w.Flush(); err != nil {
+        tmp.Close()
+        os.Remove(tmpPath)
+        return err
+    }
+    if err := tmp.Sync(); err != nil {
+        tmp.Close()
+        os.Remove(tmpPath)
+        return err
+    }
+    if err := os.Rename(tmpPath, db.path); err != nil {
+        tmp.Close()
+        os.Remove(tmpPath)
+        return err
+    }
+    syncDir(filepath.Dir(db.path))
+    db.file.Close()
+    db.file = tmp
+    db.size = size
+    return nil
+}
+
+func syncDir(dir string) error {
+    d, err := os.Open(dir)
+    if err != nil {
+        return err
+    }
+    defer d.Close()
+    return d.Sync()
+}
+
+// Closes the store.
+func (db *DB) Close() error {
+    db.mu.Lock()
+    defer db.mu.Unlock()
+    if db.closed {
+        return ErrClosed
+    }
+    db.closed = true
+    return db.file.Close()
+}
@@ -0,0 +1,206 @@
+package kv
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/hakanols/binson-go"
+    "github.com/stretchr/testify/assert"
+)
+
+func user(name string, age int) binson.Binson {
+    return binson.NewBinson().
+        Put("user", name).
+        Put("profile", binson.NewBinson().
+            Put("age", age))
+}
+
+func TestPutGetDelete(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "store")
+    db, err := Open(path)
+    assert.Nil(t, err, "Got error")
+    assert.Nil(t, db.Put("a", user("alice", 30)), "Got error")
+    assert.Nil(t, db.Put("b", user("bob", 40)), "Got error")
+    assert.Nil(t, db.Delete("b"), "Got error")
+
+    v, ok := db.Get("a")
+    assert.True(t, ok, "Should have object")
+    name, _ := v.GetString("user")
+    assert.Equal(t, "alice", name, "Wrong value")
+    v.Put("user", "mallory")
+    v, _ = db.Get("a")
+    name, _ = v.GetString("user")
+    assert.Equal(t, "alice", name, "Stored value shall not change")
+    _, ok = db.Get("b")
+    assert.False(t, ok, "Should not have object")
+    assert.Nil(t, db.Close(), "Got error")
+    assert.Equal(t, ErrClosed, db.Put("c", user("carol", 1)), "Should get error")
+
+    db, err = Open(path)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, 1, db.Len(), "Wrong length")
+    _, ok = db.Get("a")
+    assert.True(t, ok, "Should have object")
+    db.Close()
+}
+
+func TestBatchAndTornTail(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "store")
+    db, _ := Open(path)
+    db.Put("x", user("xavier", 1))
+    err := db.Write(NewBatch().
+        Put("a", user("alice", 30)).
+        Put("b", user("bob", 40)).
+        Delete("x"))
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, 2, db.Len(), "Wrong length")
+    db.Close()
+
+    info, _ := os.Stat(path)
+    os.Truncate(path, info.Size() - 1)
+    db, err = Open(path)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, 1, db.Len(), "Torn batch shall be dropped as a whole")
+    _, ok := db.Get("x")
+    assert.True(t, ok, "Should have object")
+    assert.Nil(t, db.Put("c", user("carol", 50)), "Got error")
+    db.Close()
+
+    db, _ = Open(path)
+    assert.Equal(t, 2, db.Len(), "Wrong length")
+    db.Close()
+}
+
+func TestCorruptRecord(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "store")
+    db, _ := Open(path)
+    for _, key := range []string{"a", "b", "c", "d", "e"} {
+        db.Put(key, user(key, 1))
+    }
+    db.Close()
+
+    data, _ := os.ReadFile(path)
+    record := len(data) / 5
+    data[2*record + headerSize + 3] ^= 0x01
+    os.WriteFile(path, data, 0644)
+    _, err := Open(path)
+    assert.True(t, errors.Is(err, ErrCorrupt), "Should get error")
+    info, _ := os.Stat(path)
+    assert.Equal(t, int64(len(data)), info.Size(), "File shall be left unchanged")
+}
+
+func TestZeroedTail(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "store")
+    db, _ := Open(path)
+    db.Put("a", user("alice", 30))
+    db.Put("b", user("bob", 40))
+    db.Close()
+
+    data, _ := os.ReadFile(path)
+    good := append([]byte{}, data...)
+    clear(data[len(data) - 20:])
+    os.WriteFile(path, data, 0644)
+    db, err := Open(path)
+    assert.Nil(t, err, "Torn record shall be removed")
+    assert.Equal(t, 1, db.Len(), "Wrong length")
+    _, ok := db.Get("a")
+    assert.True(t, ok, "Should have object")
+    db.Close()
+
+    os.WriteFile(path, append(good, make([]byte, 100)...), 0644)
+    db, err = Open(path)
+    assert.Nil(t, err, "Zero filled tail shall be removed")
+    assert.Equal(t, 2, db.Len(), "Wrong length")
+    db.Close()
+    info, _ := os.Stat(path)
+    assert.Equal(t, int64(len(good)), info.Size(), "Wrong size")
+}
+
+func TestNilValue(t *testing.T) {
+    db, _ := Open(filepath.Join(t.TempDir(), "store"))
+    defer db.Close()
+    db.Put("a", user("alice", 30))
+    batch := NewBatch().Put("b", user("bob", 40)).Put("a", nil)
+    assert.True(t, errors.Is(batch.Err(), ErrNilValue), "Should get error")
+    assert.True(t, errors.Is(db.Write(batch), ErrNilValue), "Should get error")
+    assert.True(t, errors.Is(db.Put("a", nil), ErrNilValue), "Should get error")
+    assert.Equal(t, 1, db.Len(), "Batch shall not be applied")
+    _, ok := db.Get("a")
+    assert.True(t, ok, "Should have object")
+}
+
+func TestScan(t *testing.T) {
+    db, _ := Open(filepath.Join(t.TempDir(), "store"))
+    defer db.Close()
+    for _, key := range []string{"user/b", "user/a", "group/a", "user/c"} {
+        db.Put(key, user(key, 1))
+    }
+    keys := []string{}
+    for key, value := range db.Scan("user/") {
+        keys = append(keys, key)
+        assert.True(t, value.HasString("user"), "Should have object")
+    }
+    assert.Equal(t, []string{"user/a", "user/b", "user/c"}, keys, "Keys do not match")
+    for key := range db.Scan("") {
+        assert.Equal(t, "group/a", key, "Should stop after first")
+        break
+    }
+}
+
+func TestIndex(t *testing.T) {
+    db, _ := Open(filepath.Join(t.TempDir(), "store"))
+    defer db.Close()
+    db.Put("1", user("alice", 30))
+    db.Put("2", user("bob", 30))
+    db.Put("3", binson.NewBinson().Put("other", 1))
+
+    assert.Nil(t, db.CreateIndex("user", binson.Path{"user"}), "Got error")
+    assert.Nil(t, db.CreateIndex("age", binson.Path{"profile", "age"}), "Got error")
+    keys, err := db.Find("user", "alice")
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, []string{"1"}, keys, "Keys do not match")
+    keys, _ = db.Find("age", 30)
+    assert.Equal(t, []string{"1", "2"}, keys, "Keys do not match")
+
+    db.Put("2", user("bob", 31))
+    db.Put("4", user("alice", 30))
+    db.Delete("1")
+    keys, _ = db.Find("age", 30)
+    assert.Equal(t, []string{"4"}, keys, "Keys do not match")
+    keys, _ = db.Find("user", "alice")
+    assert.Equal(t, []string{"4"}, keys, "Keys do not match")
+    keys, _ = db.Find("user", "nobody")
+    assert.Equal(t, []string{}, keys, "Keys do not match")
+
+    _, err = db.Find("missing", "alice")
+    assert.True(t, errors.Is(err, ErrNoIndex), "Should get error")
+    db.DropIndex("user")
+    _, err = db.Find("user", "alice")
+    assert.True(t, errors.Is(err, ErrNoIndex), "Should get error")
+}
+
+func TestCompact(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "store")
+    db, _ := Open(path)
+    for i := 0; i < 100; i++ {
+        db.Put("key", user("alice", i))
+    }
+    db.Put("other", user("bob", 1))
+    before, _ := os.Stat(path)
+    assert.Nil(t, db.Compact(), "Got error")
+    after, _ := os.Stat(path)
+    assert.True(t, after.Size() < before.Size() / 10, "File should shrink")
+    assert.Nil(t, db.Put("new", user("carol", 2)), "Got error")
+    db.Close()
+
+    db, err := Open(path)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, 3, db.Len(), "Wrong length")
+    v, _ := db.Get("key")
+    profile, _ := v.GetBinson("profile")
+    age, _ := profile.GetInt("age")
+    assert.Equal(t, int64(99), age, "Wrong value")
+    db.Close()
+}
@@ -0,0 +1,469 @@
+// Package log is an append-only log of Binson records stored on disk.
+//
+// Records are numbered by sequence from zero and written to segment files
+// that are rotated when they reach a size limit. Each record carries a
+// CRC-32C checksum, and each segment has a sidecar index with the offset of
+// every record for random access by sequence number. An incomplete or bad
+// record left at the end of the last segment by a crash is cut off when the
+// log is opened, while a bad record followed by other data makes Open fail
+// with ErrCorrupt.
+package log
+
+import (
+    "bufio"
+    "context"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "hash/crc32"
+    "io"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+
+    "github.com/hakanols/binson-go"
+)
+
+const (
+    // DefaultMaxSegmentSize is the segment size limit used when none is given.
+    DefaultMaxSegmentSize = 64 * 1024 * 1024
+
+    headerSize = 8
+    indexEntrySize = 8
+    segmentExt = ".seg"
+    indexExt = ".idx"
+)
+
+var (
+    // ErrNotFound is returned when reading a sequence number not in the log.
+    ErrNotFound = errors.New("Record not found")
+    // ErrCorrupt is returned when a stored record does not match its checksum.
+    ErrCorrupt = errors.New("Record corrupt")
+    // ErrClosed is returned when using a closed log.
+    ErrClosed = errors.New("Log closed")
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Options configures a Log.
+type Options struct {
+    // MaxSegmentSize is the size in bytes at which a new segment is started.
+    MaxSegmentSize int64
+}
+
+type segment struct {
+    first uint64
+    count uint64
+    size int64
+    data *os.File
+    index *os.File
+}
+
+// Log is an append-only log of Binson records in a directory. It is safe
+// for concurrent use.
+type Log struct {
+    mu sync.RWMutex
+    dir string
+    maxSize int64
+    segments []*segment
+    notify chan struct{}
+    closed bool
+}
+
+func segmentName(dir string, first uint64, ext string) string {
+    return filepath.Join(dir, fmt.Sprintf("%020d%s", first, ext))
+}
+
+// Opens the log in dir, creating it if needed. The last segment is checked
+// and an incomplete or bad record at its end is removed. A bad record
+// followed by other data makes Open return ErrCorrupt.
+func Open(dir string, opts *Options) (*Log, error) {
+    l := &Log{
+        dir: dir,
+        maxSize: DefaultMaxSegmentSize,
+        notify: make(chan struct{}),
+    }
+    if opts != nil && opts.MaxSegmentSize > 0 {
+        l.maxSize = opts.MaxSegmentSize
+    }
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return nil, err
+    }
+    firsts, err := listSegments(dir)
+    if err != nil {
+        return nil, err
+    }
+    if len(firsts) == 0 {
+        seg, err := createSegment(dir, 0)
+        if err != nil {
+            return nil, err
+        }
+        l.segments = append(l.segments, seg)
+        return l, nil
+    }
+    for i, first := range firsts {
+        seg, err := openSegment(dir, first, i == len(firsts)-1)
+        if err != nil {
+            l.closeFiles()
+            return nil, err
+        }
+        if i > 0 {
+            prev := l.segments[i-1]
+            if prev.first + prev.count != seg.first {
+                seg.close()
+                l.closeFiles()
+                return nil, fmt.Errorf("%w: segment %d does not follow %d", ErrCorrupt, seg.first, prev.first)
+            }
+        }
+        l.segments = append(l.segments, seg)
+    }
+    return l, nil
+}
+
+func listSegments(dir string) ([]uint64, error) {
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        return nil, err
+    }
+    firsts := []uint64{}
+    for _, entry := range entries {
+        name := entry.Name()
+        if !strings.HasSuffix(name, segmentExt) {
+            continue
+        }
+        first, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+        if err != nil {
+            continue
+        }
+        firsts = append(firsts, first)
+    }
+    sort.Slice(firsts, func(i, j int) bool { return firsts[i] < firsts[j] })
+    return firsts, nil
+}
+
+func createSegment(dir string, first uint64) (*segment, error) {
+    data, err := os.OpenFile(segmentName(dir, first, segmentExt), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+    if err != nil {
+        return nil, err
+    }
+    index, err := os.OpenFile(segmentName(dir, first, indexExt), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+    if err != nil {
+        data.Close()
+        return nil, err
+    }
+    return &segment{first: first, data: data, index: index}, nil
+}
+
+// Opens an existing segment. The index of the last segment is always
+// rebuilt, other indexes only if they are missing or damaged.
+func openSegment(dir string, first uint64, last bool) (*segment, error) {
+    flag := os.O_RDONLY
+    if last {
+        flag = os.O_RDWR
+    }
+    data, err := os.OpenFile(segmentName(dir, first, segmentExt), flag, 0)
+    if err != nil {
+        return nil, err
+    }
+    index, err := os.OpenFile(segmentName(dir, first, indexExt), os.O_RDWR|os.O_CREATE, 0644)
+    if err != nil {
+        data.Close()
+        return nil, err
+    }
+    seg := &segment{first: first, data: data, index: index}
+    dataInfo, err := data.Stat()
+    if err != nil {
+        seg.close()
+        return nil, err
+    }
+    indexInfo, err := index.Stat()
+    if err != nil {
+        seg.close()
+        return nil, err
+    }
+    seg.size = dataInfo.Size()
+    seg.count = uint64(indexInfo.Size() / indexEntrySize)
+    if last || indexInfo.Size() % indexEntrySize != 0 || (seg.count == 0 && seg.size > 0) {
+        if err := seg.recover(last); err != nil {
+            seg.close()
+            return nil, err
+        }
+    }
+    return seg, nil
+}
+
+// Scans the records of the segment and rewrites its index. If truncate is
+// set, a record at the end that is incomplete or bad is removed. A bad
+// record followed by other data gives ErrCorrupt and the segment is left
+// unchanged.
+func (s *segment) recover(truncate bool) error {
+    if _, err := s.data.Seek(0, io.SeekStart); err != nil {
+        return err
+    }
+    r := bufio.NewReader(s.data)
+    var index []byte
+    var end int64
+    header := make([]byte, headerSize)
+    for {
+        if _, err := io.ReadFull(r, header); err != nil {
+            break
+        }
+        length := binary.LittleEndian.Uint32(header)
+        if int64(length) > s.size - end - headerSize {
+            break
+        }
+        payload := make([]byte, length)
+        if _, err := io.ReadFull(r, payload); err != nil {
+            break
+        }
+        next := end + headerSize + int64(length)
+        // An empty payload is never written, it is what a zero filled
+        // header looks like.
+        if length == 0 || crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:]) {
+            torn, err := tornTail(s.data, end, next, s.size)
+            if err != nil {
+                return err
+            }
+            if !truncate || !torn {
+                return fmt.Errorf("%w: segment %d has bad checksum at offset %d", ErrCorrupt, s.first, end)
+            }
+            break
+        }
+        index = binary.LittleEndian.AppendUint64(index, uint64(end))
+        end = next
+    }
+    if end != s.size {
+        if !truncate {
+            return fmt.Errorf("%w: segment %d has bad data at offset %d", ErrCorrupt, s.first, end)
+        }
+        if err := s.data.Truncate(end); err != nil {
+            return err
+        }
+        s.size = end
+    }
+    if err := s.index.Truncate(0); err != nil {
+        return err
+    }
+    if _, err := s.index.WriteAt(index, 0); err != nil {
+        return err
+    }
+    s.count = uint64(len(index) / indexEntrySize)
+    return nil
+}
+
+// Reports whether a bad record from offset to next can be a write torn by
+// a crash, that is the record ends at the end of the file or everything
+// from offset on is zero.
+func tornTail(file *os.File, offset int64, next int64, size int64) (bool, error) {
+    if next == size {
+        return true, nil
+    }
+    r := bufio.NewReader(io.NewSectionReader(file, offset, size - offset))
+    for {
+        c, err := r.ReadByte()
+        if err == io.EOF {
+            return true, nil
+        }
+        if err != nil {
+            return false, err
+        }
+        if c != 0 {
+            return false, nil
+        }
+    }
+}
+
+func (s *segment) close() error {
+    err := s.data.Close()
+    if indexErr := s.index.Close(); err == nil {
+        err = indexErr
+    }
+    return err
+}
+
+func (s *segment) read(seq uint64) (binson.Binson, error) {
+    entry := make([]byte, indexEntrySize)
+    if _, err := s.index.ReadAt(entry, int64(seq - s.first) * indexEntrySize); err != nil {
+        return nil, err
+    }
+    offset := int64(binary.LittleEndian.Uint64(entry))
+    header := make([]byte, headerSize)
+    if _, err := s.data.ReadAt(header, offset); err != nil {
+        return nil, err
+    }
+    payload := make([]byte, binary.LittleEndian.Uint32(header))
+    if _, err := s.data.ReadAt(payload, offset + headerSize); err != nil {
+        return nil, err
+    }
+    if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:]) {
+        return nil, fmt.Errorf("%w: sequence %d", ErrCorrupt, seq)
+    }
+    return binson.Parse(payload)
+}
+
+func (l *Log) closeFiles() error {
+    var err error
+    for _, seg := range l.segments {
+        if segErr := seg.close(); err == nil {
+            err = segErr
+        }
+    }
+    return err
+}
+
+// Returns the sequence number of the first record in the log.
+func (l *Log) FirstSeq() uint64 {
+    l.mu.RLock()
+    defer l.mu.RUnlock()
+    return l.segments[0].first
+}
+
+// Returns the sequence number the next appended record will get.
+func (l *Log) NextSeq() uint64 {
+    l.mu.RLock()
+    defer l.mu.RUnlock()
+    return l.nextSeq()
+}
+
+func (l *Log) nextSeq() uint64 {
+    last := l.segments[len(l.segments)-1]
+    return last.first + last.count
+}
+
+// Appends a record and returns its sequence number. The record is not
+// guaranteed to be on disk until Sync is called.
+func (l *Log) Append(b binson.Binson) (uint64, error) {
+    payload := b.ToBytes()
+    record := make([]byte, headerSize, headerSize + len(payload))
+    binary.LittleEndian.PutUint32(record, uint32(len(payload)))
+    binary.LittleEndian.PutUint32(record[4:], crc32.Checksum(payload, crcTable))
+    record = append(record, payload...)
+
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if l.closed {
+        return 0, ErrClosed
+    }
+    seg := l.segments[len(l.segments)-1]
+    if seg.count > 0 && l.maxSize < seg.size + int64(len(record)) {
+        var err error
+        if seg, err = l.rotate(); err != nil {
+            return 0, err
+        }
+    }
+    if _, err := seg.data.WriteAt(record, seg.size); err != nil {
+        return 0, err
+    }
+    entry := binary.LittleEndian.AppendUint64(nil, uint64(seg.size))
+    if _, err := seg.index.WriteAt(entry, int64(seg.count) * indexEntrySize); err != nil {
+        return 0, err
+    }
+    seq := seg.first + seg.count
+    seg.size += int64(len(record))
+    seg.count++
+    close(l.notify)
+    l.notify = make(chan struct{})
+    return seq, nil
+}
+
+// Syncs the current segment and starts a new one.
+func (l *Log) rotate() (*segment, error) {
+    last := l.segments[len(l.segments)-1]
+    if err := last.data.Sync(); err != nil {
+        return nil, err
+    }
+    if err := last.index.Sync(); err != nil {
+        return nil, err
+    }
+    seg, err := createSegment(l.dir, last.first + last.count)
+    if err != nil {
+        return nil, err
+    }
+    l.segments = append(l.segments, seg)
+    return seg, nil
+}
+
+// Returns the record with the given sequence number.
+func (l *Log) Read(seq uint64) (binson.Binson, error) {
+    l.mu.RLock()
+    defer l.mu.RUnlock()
+    if l.closed {
+        return nil, ErrClosed
+    }
+    i := sort.Search(len(l.segments), func(i int) bool { return seq < l.segments[i].first }) - 1
+    if i < 0 || l.segments[i].first + l.segments[i].count <= seq {
+        return nil, fmt.Errorf("%w: sequence %d", ErrNotFound, seq)
+    }
+    return l.segments[i].read(seq)
+}
+
+// Writes appended records of the current segment to disk.
+func (l *Log) Sync() error {
+    l.mu.RLock()
+    defer l.mu.RUnlock()
+    if l.closed {
+        return ErrClosed
+    }
+    last := l.segments[len(l.segments)-1]
+    if err := last.data.Sync(); err != nil {
+        return err
+    }
+    return last.index.Sync()
+}
+
+// Syncs and closes the log. Waiting followers return ErrClosed.
+func (l *Log) Close() error {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if l.closed {
+        return ErrClosed
+    }
+    last := l.segments[len(l.segments)-1]
+    err := last.data.Sync()
+    if closeErr := l.closeFiles(); err == nil {
+        err = closeErr
+    }
+    l.closed = true
+    close(l.notify)
+    return err
+}
+
+// Follower reads records in order and waits for new ones at the end of the
+// log.
+type Follower struct {
+    l *Log
+    next uint64
+}
+
+// Returns a Follower starting at the given sequence number.
+func (l *Log) Follow(from uint64) *Follower {
+    return &Follower{l: l, next: from}
+}
+
+// Returns the next record, waiting until it is appended or ctx is done.
+func (f *Follower) Next(ctx context.Context) (uint64, binson.Binson, error) {
+    for {
+        f.l.mu.RLock()
+        next, notify, closed := f.l.nextSeq(), f.l.notify, f.l.closed
+        f.l.mu.RUnlock()
+        if closed {
+            return 0, nil, ErrClosed
+        }
+        if f.next < next {
+            b, err := f.l.Read(f.next)
+            if err != nil {
+                return 0, nil, err
+            }
+            f.next++
+            return f.next - 1, b, nil
+        }
+        select {
+            case <-notify:
+            case <-ctx.Done():
+                return 0, nil, ctx.Err()
+        }
+    }
+}
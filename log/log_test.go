@@ -0,0 +1,168 @@
+package log
+
+import (
+    "context"
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "github.com/hakanols/binson-go"
+    "github.com/stretchr/testify/assert"
+)
+
+func record(i int) binson.Binson {
+    return binson.NewBinson().
+        Put("i", i).
+        Put("data", make([]byte, 100))
+}
+
+func TestAppendRead(t *testing.T) {
+    dir := t.TempDir()
+    l, err := Open(dir, &Options{MaxSegmentSize: 1000})
+    assert.Nil(t, err, "Got error")
+    for i := 0; i < 30; i++ {
+        seq, err := l.Append(record(i))
+        assert.Nil(t, err, "Got error")
+        assert.Equal(t, uint64(i), seq, "Wrong sequence")
+    }
+    assert.Equal(t, uint64(0), l.FirstSeq(), "Wrong first")
+    assert.Equal(t, uint64(30), l.NextSeq(), "Wrong next")
+    for _, i := range []int{0, 7, 8, 29} {
+        b, err := l.Read(uint64(i))
+        assert.Nil(t, err, "Got error")
+        n, _ := b.GetInt("i")
+        assert.Equal(t, int64(i), n, "Wrong value")
+    }
+    _, err = l.Read(30)
+    assert.True(t, errors.Is(err, ErrNotFound), "Should get error")
+
+    segments, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+    assert.Equal(t, 4, len(segments), "Should rotate segments")
+    assert.Nil(t, l.Close(), "Got error")
+    _, err = l.Append(record(0))
+    assert.Equal(t, ErrClosed, err, "Should get error")
+
+    l, err = Open(dir, &Options{MaxSegmentSize: 1000})
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, uint64(30), l.NextSeq(), "Wrong next")
+    seq, err := l.Append(record(30))
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, uint64(30), seq, "Wrong sequence")
+    b, err := l.Read(17)
+    assert.Nil(t, err, "Got error")
+    n, _ := b.GetInt("i")
+    assert.Equal(t, int64(17), n, "Wrong value")
+    assert.Nil(t, l.Close(), "Got error")
+}
+
+func TestRecoverTornTail(t *testing.T) {
+    dir := t.TempDir()
+    l, _ := Open(dir, nil)
+    for i := 0; i < 5; i++ {
+        l.Append(record(i))
+    }
+    l.Close()
+
+    name := filepath.Join(dir, "00000000000000000000.seg")
+    info, _ := os.Stat(name)
+    assert.Nil(t, os.Truncate(name, info.Size() - 10), "Got error")
+    l, err := Open(dir, nil)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, uint64(4), l.NextSeq(), "Torn record should be removed")
+    seq, _ := l.Append(record(4))
+    assert.Equal(t, uint64(4), seq, "Wrong sequence")
+    l.Close()
+
+    // A bad record in the middle is reported and the segment kept.
+    data, _ := os.ReadFile(name)
+    bad := append([]byte{}, data...)
+    bad[20] ^= 0xff
+    os.WriteFile(name, bad, 0644)
+    _, err = Open(dir, nil)
+    assert.True(t, errors.Is(err, ErrCorrupt), "Should get error")
+    info, _ = os.Stat(name)
+    assert.Equal(t, int64(len(data)), info.Size(), "Segment shall be left unchanged")
+
+    // A bad last record or a zero filled tail is a torn write.
+    for want, torn := range map[uint64][]byte{
+        4: append(append([]byte{}, data[:len(data) - 20]...), make([]byte, 20)...),
+        5: append(append([]byte{}, data...), make([]byte, 50)...),
+    } {
+        os.WriteFile(name, torn, 0644)
+        l, err = Open(dir, nil)
+        assert.Nil(t, err, "Got error")
+        assert.Equal(t, want, l.NextSeq(), "Wrong next sequence")
+        _, err = l.Read(3)
+        assert.Nil(t, err, "Got error")
+        l.Close()
+    }
+}
+
+func TestRebuildIndex(t *testing.T) {
+    dir := t.TempDir()
+    l, _ := Open(dir, &Options{MaxSegmentSize: 500})
+    for i := 0; i < 10; i++ {
+        l.Append(record(i))
+    }
+    l.Close()
+    assert.Nil(t, os.Remove(filepath.Join(dir, "00000000000000000000.idx")), "Got error")
+    l, err := Open(dir, &Options{MaxSegmentSize: 500})
+    assert.Nil(t, err, "Got error")
+    b, err := l.Read(1)
+    assert.Nil(t, err, "Got error")
+    n, _ := b.GetInt("i")
+    assert.Equal(t, int64(1), n, "Wrong value")
+    l.Close()
+}
+
+func TestCorruptRecord(t *testing.T) {
+    dir := t.TempDir()
+    l, _ := Open(dir, nil)
+    l.Append(record(0))
+    l.Append(record(1))
+    f, _ := os.OpenFile(filepath.Join(dir, "00000000000000000000.seg"), os.O_RDWR, 0)
+    f.WriteAt([]byte{0xff}, 20)
+    f.Close()
+    _, err := l.Read(0)
+    assert.True(t, errors.Is(err, ErrCorrupt), "Should get error")
+    _, err = l.Read(1)
+    assert.Nil(t, err, "Got error")
+    l.Close()
+}
+
+func TestFollow(t *testing.T) {
+    l, _ := Open(t.TempDir(), nil)
+    l.Append(record(0))
+    f := l.Follow(0)
+    ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+    defer cancel()
+
+    seq, b, err := f.Next(ctx)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, uint64(0), seq, "Wrong sequence")
+    assert.True(t, b.HasInt("i"), "Should have object")
+
+    go func() {
+        time.Sleep(10 * time.Millisecond)
+        l.Append(record(1))
+    }()
+    seq, b, err = f.Next(ctx)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, uint64(1), seq, "Wrong sequence")
+    n, _ := b.GetInt("i")
+    assert.Equal(t, int64(1), n, "Wrong value")
+
+    short, cancelShort := context.WithTimeout(ctx, 10 * time.Millisecond)
+    defer cancelShort()
+    _, _, err = f.Next(short)
+    assert.Equal(t, context.DeadlineExceeded, err, "Should time out")
+
+    go func() {
+        time.Sleep(10 * time.Millisecond)
+        l.Close()
+    }()
+    _, _, err = f.Next(ctx)
+    assert.Equal(t, ErrClosed, err, "Should get error")
+}
@@ -1,6 +1,11 @@
 package binson
 
 import (
+    "bytes"
+    "errors"
+    "fmt"
+    "io"
+    "math"
     "testing"
     "encoding/hex"
     "github.com/stretchr/testify/assert"
@@ -268,4 +273,1186 @@ func TestParseArray(t *testing.T) {
     assert.True(t, ok, "Should have object")
     _, ok = arr.GetBool(0)
     assert.False(t, ok, "Should have object")
-}
\ No newline at end of file
+}
+type testPoint struct {
+    x, y int64
+}
+
+func (p testPoint) MarshalBinson() (interface{}, error) {
+    return NewBinson().Put("x", p.x).Put("y", p.y), nil
+}
+
+func (p *testPoint) UnmarshalBinson(value interface{}) error {
+    b, ok := value.(Binson)
+    if !ok {
+        return fmt.Errorf("Not a Binson: %T", value)
+    }
+    p.x, _ = b.GetInt("x")
+    p.y, _ = b.GetInt("y")
+    return nil
+}
+
+type testFailing struct{}
+
+func (testFailing) MarshalBinson() (interface{}, error) {
+    return nil, fmt.Errorf("always fails")
+}
+
+type testSelf struct{}
+
+func (s testSelf) MarshalBinson() (interface{}, error) {
+    return s, nil
+}
+
+type testWrap struct {
+    v interface{}
+}
+
+func (w testWrap) MarshalBinson() (interface{}, error) {
+    return w.v, nil
+}
+
+func TestMarshaler(t *testing.T) {
+    want, _ := hex.DecodeString("4014016140140178100114017910024141")
+    b := NewBinson().
+        Put("a", testPoint{1, 2})
+    assert.Equal(t, want, b.ToBytes(), "Bytes do not match")
+
+    want, _ = hex.DecodeString("42401401781001140179100241404143")
+    a := NewBinsonArray().
+        Put(testPoint{1, 2}).
+        Put(NewBinson())
+    assert.Equal(t, want, a.ToBytes(), "Bytes do not match")
+
+    assert.Panics(t, func() { NewBinson().Put("a", testFailing{}) }, "Should panic")
+    assert.Panics(t, func() { NewBinsonArray().Put(testFailing{}) }, "Should panic")
+
+    assert.NotNil(t, NewBinson().TryPut("a", testSelf{}), "Should get error")
+    assert.NotNil(t, NewBinsonArray().TryPut(testSelf{}), "Should get error")
+    var deep interface{} = int64(7)
+    for i := 0; i < maxMarshalDepth; i++ {
+        deep = testWrap{deep}
+    }
+    b = NewBinson()
+    assert.Nil(t, b.TryPut("a", deep), "Got error")
+    assert.NotNil(t, b.TryPut("b", testWrap{deep}), "Should get error")
+}
+
+func TestUnmarshaler(t *testing.T) {
+    b := NewBinson().
+        Put("a", testPoint{3, 4}).
+        Put("b", "text")
+    var p testPoint
+    assert.Nil(t, b.Unmarshal("a", &p), "Got error")
+    assert.Equal(t, testPoint{3, 4}, p, "Wrong value")
+    assert.NotNil(t, b.Unmarshal("b", &p), "Should get error")
+    assert.NotNil(t, b.Unmarshal("x", &p), "Should get error")
+
+    a := NewBinsonArray().
+        Put(testPoint{5, 6})
+    assert.Nil(t, a.Unmarshal(0, &p), "Got error")
+    assert.Equal(t, testPoint{5, 6}, p, "Wrong value")
+    assert.NotNil(t, a.Unmarshal(1, &p), "Should get error")
+}
+
+func TestPutNumeric(t *testing.T) {
+    want, _ := hex.DecodeString("40140161100114016210FE14016310FD14016410FC1401651005140166100614016710071401681008140169100914016A46000000000000F83F41")
+    b := NewBinson().
+        Put("a", int8(1)).
+        Put("b", int16(-2)).
+        Put("c", int16(-3)).
+        Put("d", int32(-4)).
+        Put("e", uint(5)).
+        Put("f", uint16(6)).
+        Put("g", uint32(7)).
+        Put("h", uint8(8)).
+        Put("i", uint64(9)).
+        Put("j", float32(1.5))
+    assert.Equal(t, want, b.ToBytes(), "Bytes do not match")
+
+    assert.Panics(t, func() { NewBinson().Put("a", uint64(math.MaxUint64)) }, "Should panic")
+    assert.Panics(t, func() { NewBinsonArray().Put(uint64(math.MaxInt64) + 1) }, "Should panic")
+    assert.NotPanics(t, func() { NewBinsonArray().Put(uint64(math.MaxInt64)) }, "Should not panic")
+}
+
+func TestGetNumeric(t *testing.T) {
+    b := NewBinson().
+        Put("a", 100).
+        Put("b", 1000).
+        Put("c", -1).
+        Put("d", math.MaxInt64).
+        Put("e", 1.5).
+        Put("f", math.MaxFloat64).
+        Put("g", "text")
+
+    i8, ok := b.GetInt8("a")
+    assert.Equal(t, int8(100), i8, "Wrong value")
+    assert.True(t, ok, "Should fit")
+    _, ok = b.GetInt8("b")
+    assert.False(t, ok, "Should overflow")
+    _, ok = b.GetUint8("b")
+    assert.False(t, ok, "Should overflow")
+    u16, ok := b.GetUint16("b")
+    assert.Equal(t, uint16(1000), u16, "Wrong value")
+    assert.True(t, ok, "Should fit")
+    i16, ok := b.GetInt16("c")
+    assert.Equal(t, int16(-1), i16, "Wrong value")
+    assert.True(t, ok, "Should fit")
+    _, ok = b.GetUint64("c")
+    assert.False(t, ok, "Should overflow")
+    _, ok = b.GetUint("c")
+    assert.False(t, ok, "Should overflow")
+    _, ok = b.GetUint32("c")
+    assert.False(t, ok, "Should overflow")
+    _, ok = b.GetInt32("d")
+    assert.False(t, ok, "Should overflow")
+    u64, ok := b.GetUint64("d")
+    assert.Equal(t, uint64(math.MaxInt64), u64, "Wrong value")
+    assert.True(t, ok, "Should fit")
+    _, ok = b.GetInt32("g")
+    assert.False(t, ok, "Should not have object")
+    _, ok = b.GetInt32("x")
+    assert.False(t, ok, "Should not have object")
+
+    f32, ok := b.GetFloat32("e")
+    assert.Equal(t, float32(1.5), f32, "Wrong value")
+    assert.True(t, ok, "Should fit")
+    _, ok = b.GetFloat32("f")
+    assert.False(t, ok, "Should overflow")
+
+    a := NewBinsonArray().
+        Put(uint8(200)).
+        Put(-200).
+        Put(float32(2.5))
+    u8, ok := a.GetUint8(0)
+    assert.Equal(t, uint8(200), u8, "Wrong value")
+    assert.True(t, ok, "Should fit")
+    _, ok = a.GetInt8(0)
+    assert.False(t, ok, "Should overflow")
+    i32, ok := a.GetInt32(1)
+    assert.Equal(t, int32(-200), i32, "Wrong value")
+    assert.True(t, ok, "Should fit")
+    _, ok = a.GetUint16(1)
+    assert.False(t, ok, "Should overflow")
+    f32, ok = a.GetFloat32(2)
+    assert.Equal(t, float32(2.5), f32, "Wrong value")
+    assert.True(t, ok, "Should fit")
+}
+
+func TestTryPut(t *testing.T) {
+    b := NewBinson()
+    assert.Nil(t, b.TryPut("a", 1), "Got error")
+    assert.NotNil(t, b.TryPut("b", struct{}{}), "Should get error")
+    assert.NotNil(t, b.TryPut("c", testFailing{}), "Should get error")
+    assert.True(t, errors.Is(b.TryPut("d", uint64(math.MaxUint64)), ErrOverflow), "Should overflow")
+    assert.Equal(t, []string{"a"}, b.FieldNames(), "Keys do not match")
+
+    a := NewBinsonArray()
+    assert.Nil(t, a.TryPut(1), "Got error")
+    assert.NotNil(t, a.TryPut(map[string]interface{}{}), "Should get error")
+    assert.Equal(t, 1, a.Size(), "Wrong length")
+    assert.NotNil(t, a.TryAppend(2, 3, struct{}{}), "Should get error")
+    assert.Equal(t, 1, a.Size(), "Array should be unchanged")
+    assert.Nil(t, a.TryAppend(2, "b", true), "Got error")
+    want, _ := hex.DecodeString("42100110021401624443")
+    assert.Equal(t, want, a.ToBytes(), "Bytes do not match")
+}
+
+func TestBuilder(t *testing.T) {
+    want, _ := hex.DecodeString("4014016110011401624210024341")
+    b, err := NewBuilder().
+        Put("a", 1).
+        Put("b", NewBinsonArray().Put(2)).
+        Binson()
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, want, b.ToBytes(), "Bytes do not match")
+
+    builder := NewBuilder().
+        Put("a", 1).
+        Put("b", struct{}{}).
+        Put("c", testFailing{})
+    assert.EqualError(t, builder.Err(), "struct {} is not handeled by Binson", "Should keep first error")
+    b, err = builder.Binson()
+    assert.Nil(t, b, "Shall not get any Binson on fail")
+    assert.NotNil(t, err, "Should get error")
+
+    a, err := NewArrayBuilder().
+        Put(1).
+        Put("b").
+        Array()
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, 2, a.Size(), "Wrong length")
+
+    arrayBuilder := NewArrayBuilder().
+        Put(1).
+        Put(testFailing{}).
+        Put(struct{}{})
+    assert.Contains(t, arrayBuilder.Err().Error(), "always fails", "Should keep first error")
+    a, err = arrayBuilder.Array()
+    assert.Nil(t, a, "Shall not get any array on fail")
+    assert.NotNil(t, err, "Should get error")
+}
+
+func TestFromValue(t *testing.T) {
+    want, _ := hex.DecodeString("401401611001140162401401634214016445431401651801014141")
+    b, err := FromValue(map[string]interface{}{
+        "a": 1,
+        "b": map[string]interface{}{
+            "c": []interface{}{"d", false},
+            "e": []byte{1},
+        },
+    })
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, want, b.ToBytes(), "Bytes do not match")
+
+    _, err = FromValue([]interface{}{1})
+    assert.NotNil(t, err, "Should get error")
+    _, err = FromValue(map[string]interface{}{
+        "a": []interface{}{1, struct{}{}},
+    })
+    assert.EqualError(t, err, "Field a: Index 1: struct {} is not handeled by Binson", "Wrong error")
+}
+
+func TestToMap(t *testing.T) {
+    b := NewBinson().
+        Put("a", 1).
+        Put("b", NewBinson().
+            Put("c", NewBinsonArray().
+                Put("d").
+                Put(false)).
+            Put("e", []byte{1})).
+        Put("f", 1.5)
+    want := map[string]interface{}{
+        "a": int64(1),
+        "b": map[string]interface{}{
+            "c": []interface{}{"d", false},
+            "e": []byte{1},
+        },
+        "f": 1.5,
+    }
+    assert.Equal(t, want, b.ToMap(), "Map do not match")
+
+    back, err := FromValue(b.ToMap())
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, b.ToBytes(), back.ToBytes(), "Bytes do not match")
+
+    a := NewBinsonArray().
+        Put(NewBinsonArray()).
+        Put(NewBinson())
+    assert.Equal(t, []interface{}{[]interface{}{}, map[string]interface{}{}}, a.ToSlice(), "Slice do not match")
+}
+
+func TestValue(t *testing.T) {
+    b := NewBinson().
+        Put("a", NewBinson()).
+        Put("b", NewBinsonArray()).
+        Put("c", 1).
+        Put("d", "e").
+        Put("f", []byte{2}).
+        Put("g", true).
+        Put("h", 1.5)
+    kinds := map[string]Kind{
+        "a": BinsonKind,
+        "b": ArrayKind,
+        "c": IntKind,
+        "d": StringKind,
+        "f": BytesKind,
+        "g": BoolKind,
+        "h": FloatKind,
+    }
+    for name, kind := range kinds {
+        v, ok := b.Get(name)
+        assert.True(t, ok, "Should have object")
+        assert.Equal(t, kind, v.Kind(), "Wrong kind")
+    }
+    v, ok := b.Get("x")
+    assert.False(t, ok, "Should not have object")
+    assert.Equal(t, InvalidKind, v.Kind(), "Wrong kind")
+    assert.Nil(t, v.Interface(), "Should be nil")
+    assert.Equal(t, "Invalid", v.Kind().String(), "Wrong name")
+    assert.Equal(t, "Bytes", BytesKind.String(), "Wrong name")
+
+    v, _ = b.Get("c")
+    io, ok := v.GetInt()
+    assert.Equal(t, int64(1), io, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    _, ok = v.GetString()
+    assert.False(t, ok, "Should not have object")
+    assert.Equal(t, int64(1), v.Interface(), "Wrong value")
+    assert.Equal(t, []byte{0x10, 0x01}, v.ToBytes(), "Bytes do not match")
+
+    v, _ = b.Get("d")
+    so, ok := v.GetString()
+    assert.Equal(t, "e", so, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    v, _ = b.Get("a")
+    _, ok = v.GetBinson()
+    assert.True(t, ok, "Should have object")
+
+    c := NewBinson().Put("x", v)
+    assert.True(t, c.HasBinson("x"), "Should have object")
+    assert.Panics(t, func() { c.Put("y", Value{}) }, "Should panic")
+
+    a := NewBinsonArray().
+        Put(2.5)
+    v, ok = a.Get(0)
+    assert.True(t, ok, "Should have object")
+    fo, ok := v.GetFloat()
+    assert.Equal(t, 2.5, fo, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    _, ok = a.Get(1)
+    assert.False(t, ok, "Should not have object")
+    _, ok = a.Get(-1)
+    assert.False(t, ok, "Should not have object")
+
+    v, err := ValueOf(uint8(3))
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, IntKind, v.Kind(), "Wrong kind")
+    _, err = ValueOf(struct{}{})
+    assert.NotNil(t, err, "Should get error")
+}
+
+func TestGeneric(t *testing.T) {
+    b := NewBinson().
+        Put("a", NewBinson()).
+        Put("b", NewBinsonArray().
+            Put(1).
+            Put("c")).
+        Put("d", 2).
+        Put("e", "f").
+        Put("g", []byte{3}).
+        Put("h", true).
+        Put("i", 4.5)
+
+    bo, ok := Get[Binson](b, "a")
+    assert.Equal(t, NewBinson(), bo, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    ao, ok := Get[*BinsonArray](b, "b")
+    assert.Equal(t, 2, ao.Size(), "Wrong length")
+    assert.True(t, ok, "Should have object")
+    io, ok := Get[int64](b, "d")
+    assert.Equal(t, int64(2), io, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    so, ok := Get[string](b, "e")
+    assert.Equal(t, "f", so, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    yo, ok := Get[[]byte](b, "g")
+    assert.Equal(t, []byte{3}, yo, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    oo, ok := Get[bool](b, "h")
+    assert.Equal(t, true, oo, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    fo, ok := Get[float64](b, "i")
+    assert.Equal(t, 4.5, fo, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    _, ok = Get[string](b, "d")
+    assert.False(t, ok, "Should not have object")
+    _, ok = Get[string](b, "x")
+    assert.False(t, ok, "Should not have object")
+
+    io, ok = At[int64](ao, 0)
+    assert.Equal(t, int64(1), io, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    _, ok = At[int64](ao, 1)
+    assert.False(t, ok, "Should not have object")
+    _, ok = At[int64](ao, 2)
+    assert.False(t, ok, "Should not have object")
+
+    assert.Equal(t, "f", GetOr(b, "e", "z"), "Wrong value")
+    assert.Equal(t, "z", GetOr(b, "d", "z"), "Wrong value")
+    assert.Equal(t, "c", AtOr(ao, 1, "z"), "Wrong value")
+    assert.Equal(t, "z", AtOr(ao, 5, "z"), "Wrong value")
+
+    assert.Equal(t, int64(2), Must(Get[int64](b, "d")), "Wrong value")
+    assert.Panics(t, func() { Must(Get[int64](b, "e")) }, "Should panic")
+    assert.Panics(t, func() { Must(At[bool](ao, 9)) }, "Should panic")
+}
+
+func TestArrayOutOfRange(t *testing.T) {
+    a := NewBinsonArray().
+        Put(1)
+    _, ok := a.GetInt(1)
+    assert.False(t, ok, "Should not have object")
+    _, ok = a.GetString(-1)
+    assert.False(t, ok, "Should not have object")
+    bo, ok := a.GetBinson(5)
+    assert.False(t, ok, "Should not have object")
+    assert.Equal(t, Binson(nil), bo, "Shall not get any Binson on fail")
+    _, ok = a.GetArray(5)
+    assert.False(t, ok, "Should not have object")
+    _, ok = a.GetBytes(5)
+    assert.False(t, ok, "Should not have object")
+    _, ok = a.GetBool(5)
+    assert.False(t, ok, "Should not have object")
+    _, ok = a.GetFloat(5)
+    assert.False(t, ok, "Should not have object")
+    _, ok = a.GetUint8(5)
+    assert.False(t, ok, "Should not have object")
+    assert.True(t, errors.Is(a.Remove(1), ErrIndexOutOfRange), "Should get error")
+    assert.Equal(t, 1, a.Size(), "Wrong length")
+}
+
+func TestArraySetInsert(t *testing.T) {
+    a := NewBinsonArray().
+        Put("a").
+        Put("b")
+    assert.Nil(t, a.Set(1, "c"), "Got error")
+    assert.True(t, errors.Is(a.Set(2, "d"), ErrIndexOutOfRange), "Should get error")
+    assert.NotNil(t, a.Set(0, struct{}{}), "Should get error")
+    assert.Nil(t, a.Insert(0, "x"), "Got error")
+    assert.Nil(t, a.Insert(3, "y"), "Got error")
+    assert.Nil(t, a.Insert(2, "z"), "Got error")
+    assert.True(t, errors.Is(a.Insert(6, "d"), ErrIndexOutOfRange), "Should get error")
+    assert.True(t, errors.Is(a.Insert(-1, "d"), ErrIndexOutOfRange), "Should get error")
+    assert.NotNil(t, a.Insert(0, struct{}{}), "Should get error")
+    assert.Equal(t, []interface{}{"x", "a", "z", "c", "y"}, a.ToSlice(), "Slice do not match")
+
+    assert.Nil(t, a.Swap(0, 4), "Got error")
+    assert.True(t, errors.Is(a.Swap(0, 5), ErrIndexOutOfRange), "Should get error")
+    assert.True(t, errors.Is(a.Swap(-1, 0), ErrIndexOutOfRange), "Should get error")
+    assert.Equal(t, []interface{}{"y", "a", "z", "c", "x"}, a.ToSlice(), "Slice do not match")
+}
+
+func TestArraySlice(t *testing.T) {
+    a := NewBinsonArray().
+        Put(1).
+        Put(2).
+        Put(3).
+        Put(4)
+    s, err := a.Slice(1, 3)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, []interface{}{int64(2), int64(3)}, s.ToSlice(), "Slice do not match")
+    s.Put(5)
+    assert.Equal(t, []interface{}{int64(1), int64(2), int64(3), int64(4)}, a.ToSlice(), "Original should be unchanged")
+    s, err = a.Slice(4, 4)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, 0, s.Size(), "Wrong length")
+    _, err = a.Slice(3, 2)
+    assert.True(t, errors.Is(err, ErrIndexOutOfRange), "Should get error")
+    _, err = a.Slice(-1, 2)
+    assert.True(t, errors.Is(err, ErrIndexOutOfRange), "Should get error")
+    _, err = a.Slice(0, 5)
+    assert.True(t, errors.Is(err, ErrIndexOutOfRange), "Should get error")
+
+    a.AppendAll(NewBinsonArray().Put(5).Put(6))
+    assert.Equal(t, 6, a.Size(), "Wrong length")
+    assert.Nil(t, a.Truncate(3), "Got error")
+    assert.True(t, errors.Is(a.Truncate(4), ErrIndexOutOfRange), "Should get error")
+    assert.True(t, errors.Is(a.Truncate(-1), ErrIndexOutOfRange), "Should get error")
+    assert.Equal(t, []interface{}{int64(3), int64(2), int64(1)}, a.Reverse().ToSlice(), "Slice do not match")
+}
+
+func TestArraySort(t *testing.T) {
+    a := NewBinsonArray().
+        Put(3).
+        Put("b").
+        Put(1).
+        Put("a").
+        Put(2)
+    a.Sort(func(x Value, y Value) bool {
+        if x.Kind() != y.Kind() {
+            return x.Kind() < y.Kind()
+        }
+        xi, _ := x.GetInt()
+        yi, _ := y.GetInt()
+        return xi < yi
+    })
+    assert.Equal(t, []interface{}{int64(1), int64(2), int64(3), "b", "a"}, a.ToSlice(), "Slice do not match")
+}
+
+func TestIterators(t *testing.T) {
+    b := NewBinson().
+        Put("c", 3).
+        Put("a", 1).
+        Put("b", 2)
+    names := []string{}
+    values := []int64{}
+    for name, v := range b.All() {
+        names = append(names, name)
+        i, _ := v.GetInt()
+        values = append(values, i)
+    }
+    assert.Equal(t, []string{"a", "b", "c"}, names, "Keys do not match")
+    assert.Equal(t, []int64{1, 2, 3}, values, "Values do not match")
+    for name := range b.All() {
+        assert.Equal(t, "a", name, "Should stop after first")
+        break
+    }
+
+    a := NewBinsonArray().
+        Put("x").
+        Put("y")
+    indexes := []int{}
+    for i, v := range a.All() {
+        indexes = append(indexes, i)
+        assert.Equal(t, StringKind, v.Kind(), "Wrong kind")
+    }
+    assert.Equal(t, []int{0, 1}, indexes, "Indexes do not match")
+}
+
+func TestWalk(t *testing.T) {
+    b := NewBinson().
+        Put("a", 1).
+        Put("b", NewBinsonArray().
+            Put(NewBinson().
+                Put("c", "d")).
+            Put(2)).
+        Put("e", NewBinson().
+            Put("f", 3))
+    paths := []string{}
+    err := Walk(b, func(path Path, v Value) error {
+        paths = append(paths, path.String())
+        if path.String() == "e" {
+            return SkipTree
+        }
+        return nil
+    })
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, []string{"", "a", "b", "b[0]", "b[0].c", "b[1]", "e"}, paths, "Paths do not match")
+
+    stop := errors.New("stop")
+    count := 0
+    err = Walk(b, func(path Path, v Value) error {
+        count++
+        if v.Kind() == ArrayKind {
+            return stop
+        }
+        return nil
+    })
+    assert.Equal(t, stop, err, "Should get error")
+    assert.Equal(t, 3, count, "Should stop walk")
+
+    err = Walk(b, func(path Path, v Value) error {
+        return SkipTree
+    })
+    assert.Nil(t, err, "Got error")
+    assert.NotNil(t, Walk(struct{}{}, nil), "Should get error")
+    assert.Equal(t, "a[1][2].b", Path{"a", 1, 2, "b"}.String(), "Wrong path")
+}
+
+func TestView(t *testing.T) {
+    data, _ := hex.DecodeString("401401611004140162140467696769140163404114016442431401651803010203140166441401674614ae47e17a543e4041")
+    v, err := NewView(append(data, 0xff))
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, data, v.Bytes(), "Bytes do not match")
+    assert.Equal(t, []string{"a", "b", "c", "d", "e", "f", "g"}, v.FieldNames(), "Keys do not match")
+    assert.True(t, v.ContainsKey("g"), "Key do not exist")
+    assert.False(t, v.ContainsKey("x"), "Key should not exist")
+
+    io, ok := v.GetInt("a")
+    assert.Equal(t, int64(4), io, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    assert.False(t, v.HasInt("b"), "Should not have object")
+    so, ok := v.GetString("b")
+    assert.Equal(t, "gigi", so, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    assert.False(t, v.HasString("a"), "Should not have object")
+    bo, ok := v.GetBinson("c")
+    assert.Equal(t, []byte{0x40, 0x41}, bo.Bytes(), "Bytes do not match")
+    assert.True(t, ok, "Should have object")
+    assert.False(t, v.HasBinson("d"), "Should not have object")
+    ao, ok := v.GetArray("d")
+    assert.Equal(t, 0, ao.Size(), "Wrong length")
+    assert.True(t, ok, "Should have object")
+    assert.False(t, v.HasArray("c"), "Should not have object")
+    yo, ok := v.GetBytes("e")
+    assert.Equal(t, []byte{1, 2, 3}, yo, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    assert.False(t, v.HasBytes("b"), "Should not have object")
+    oo, ok := v.GetBool("f")
+    assert.Equal(t, true, oo, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    assert.False(t, v.HasBool("g"), "Should not have object")
+    fo, ok := v.GetFloat("g")
+    assert.Equal(t, 30.33, fo, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    assert.False(t, v.HasFloat("x"), "Should not have object")
+
+    obj, err := v.ToBinson()
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, data, obj.ToBytes(), "Bytes do not match")
+
+    allocs := testing.AllocsPerRun(100, func() {
+        v.GetInt("a")
+        v.GetBytes("e")
+        v.GetBinson("c")
+        v.HasString("b")
+    })
+    assert.Equal(t, 0.0, allocs, "Lookups should not allocate")
+}
+
+func TestArrayView(t *testing.T) {
+    data, _ := hex.DecodeString("421004140467696769404142431803010203454614ae47e17a543e4043")
+    a, err := NewArrayView(data)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, 7, a.Size(), "Wrong length")
+    io, ok := a.GetInt(0)
+    assert.Equal(t, int64(4), io, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    so, ok := a.GetString(1)
+    assert.Equal(t, "gigi", so, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    assert.True(t, a.HasBinson(2), "Should have object")
+    assert.True(t, a.HasArray(3), "Should have object")
+    assert.True(t, a.HasBytes(4), "Should have object")
+    oo, ok := a.GetBool(5)
+    assert.Equal(t, false, oo, "Wrong value")
+    assert.True(t, ok, "Should have object")
+    assert.True(t, a.HasFloat(6), "Should have object")
+    assert.False(t, a.HasInt(7), "Should not have object")
+    assert.False(t, a.HasInt(-1), "Should not have object")
+    assert.False(t, a.HasString(0), "Should not have object")
+}
+
+func TestViewMalformed(t *testing.T) {
+    for _, s := range []string{"", "41", "40", "4014", "40140161", "401401611004", "4010014141", "40140161150a0041", "4014016199", "40140161464141"} {
+        data, _ := hex.DecodeString(s)
+        _, err := NewView(data)
+        assert.NotNil(t, err, "Should get error for %s", s)
+    }
+    for _, s := range []string{"", "40", "42", "4210"} {
+        data, _ := hex.DecodeString(s)
+        _, err := NewArrayView(data)
+        assert.NotNil(t, err, "Should get error for %s", s)
+    }
+    var v View
+    assert.False(t, v.HasInt("a"), "Should not have object")
+}
+
+func TestEncode(t *testing.T) {
+    obj := benchmarkObject()
+    data := obj.ToBytes()
+    assert.Equal(t, len(data), obj.EncodedSize(), "Wrong size")
+    back, err := Parse(data)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, data, back.ToBytes(), "Bytes do not match")
+
+    prefix := []byte{1, 2}
+    assert.Equal(t, append([]byte{1, 2}, data...), obj.AppendBinson(prefix), "Bytes do not match")
+    var buf bytes.Buffer
+    n, err := obj.WriteTo(&buf)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, int64(len(data)), n, "Wrong size")
+    assert.Equal(t, data, buf.Bytes(), "Bytes do not match")
+
+    a, _ := obj.GetArray("items")
+    data = a.ToBytes()
+    assert.Equal(t, len(data), a.EncodedSize(), "Wrong size")
+    assert.Equal(t, data, a.AppendBinson(nil), "Bytes do not match")
+    buf.Reset()
+    n, err = a.WriteTo(&buf)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, int64(len(data)), n, "Wrong size")
+    assert.Equal(t, data, buf.Bytes(), "Bytes do not match")
+
+    for _, length := range []int{0, 127, 128, 32767, 32768} {
+        b := NewBinson().
+            Put("s", string(make([]byte, length))).
+            Put("b", make([]byte, length))
+        assert.Equal(t, len(b.ToBytes()), b.EncodedSize(), "Wrong size")
+        back, err := Parse(b.ToBytes())
+        assert.Nil(t, err, "Got error")
+        yo, _ := back.GetBytes("b")
+        assert.Equal(t, length, len(yo), "Wrong length")
+    }
+    for _, value := range []int64{0, -128, 128, -32769, 32768, math.MinInt32 - 1, math.MaxInt64} {
+        b := NewBinson().Put("i", value)
+        assert.Equal(t, len(b.ToBytes()), b.EncodedSize(), "Wrong size")
+        back, _ := Parse(b.ToBytes())
+        io, _ := back.GetInt("i")
+        assert.Equal(t, value, io, "Wrong value")
+    }
+}
+
+func TestParseMalformed(t *testing.T) {
+    for _, s := range []string{"", "41", "42", "40", "4014", "40140161", "401401611004", "4010014141", "40140161150a0041", "4014016199", "40140161464141", "4014016142"} {
+        data, _ := hex.DecodeString(s)
+        _, err := Parse(data)
+        assert.NotNil(t, err, "Should get error for %s", s)
+    }
+    data, _ := hex.DecodeString("40140161180301020341")
+    obj, err := Parse(data)
+    assert.Nil(t, err, "Got error")
+    data[5] = 9
+    yo, _ := obj.GetBytes("a")
+    assert.Equal(t, []byte{1, 2, 3}, yo, "Bytes shall not alias the input")
+}
+
+func benchmarkObject() Binson {
+    items := NewBinsonArray()
+    for i := 0; i < 20; i++ {
+        items.Put(NewBinson().
+            Put("id", i * 1000).
+            Put("name", "item").
+            Put("price", 9.95).
+            Put("tags", NewBinsonArray().Put("a").Put("b")))
+    }
+    return NewBinson().
+        Put("type", "order").
+        Put("id", int64(1234567890123)).
+        Put("paid", true).
+        Put("payload", make([]byte, 1024)).
+        Put("customer", NewBinson().
+            Put("name", "Alice").
+            Put("address", NewBinson().
+                Put("street", "Main street 1").
+                Put("zip", 12345))).
+        Put("items", items)
+}
+
+func BenchmarkToBytes(b *testing.B) {
+    obj := benchmarkObject()
+    b.ReportAllocs()
+    for i := 0; i < b.N; i++ {
+        obj.ToBytes()
+    }
+}
+
+func BenchmarkAppendBinson(b *testing.B) {
+    obj := benchmarkObject()
+    buf := make([]byte, 0, obj.EncodedSize())
+    b.ReportAllocs()
+    for i := 0; i < b.N; i++ {
+        buf = obj.AppendBinson(buf[:0])
+    }
+}
+
+func BenchmarkEncodedSize(b *testing.B) {
+    obj := benchmarkObject()
+    b.ReportAllocs()
+    for i := 0; i < b.N; i++ {
+        obj.EncodedSize()
+    }
+}
+
+func benchmarkCorpus() [][]byte {
+    samples := NewBinsonArray()
+    for i := 0; i < 100; i++ {
+        samples.Put(float64(i) * 0.5)
+    }
+    return [][]byte{
+        benchmarkObject().ToBytes(),
+        NewBinson().
+            Put("c", "f").
+            Put("i", 1).
+            Put("o", "login").
+            Put("z", NewBinson().
+                Put("user", "alice").
+                Put("token", make([]byte, 32))).
+            ToBytes(),
+        NewBinson().
+            Put("sensor", "temp-04").
+            Put("ts", int64(1700000000000)).
+            Put("samples", samples).
+            ToBytes(),
+    }
+}
+
+func BenchmarkParse(b *testing.B) {
+    corpus := benchmarkCorpus()
+    b.ReportAllocs()
+    for i := 0; i < b.N; i++ {
+        for _, data := range corpus {
+            if _, err := Parse(data); err != nil {
+                b.Fatal(err)
+            }
+        }
+    }
+}
+
+func TestReset(t *testing.T) {
+    b := NewBinson().
+        Put("a", 1).
+        Put("b", 2)
+    b.Reset()
+    assert.Equal(t, []string{}, b.FieldNames(), "Keys do not match")
+    a := NewBinsonArray().
+        Put(1).
+        Put(2)
+    a.Reset()
+    assert.Equal(t, 0, a.Size(), "Wrong length")
+    assert.Equal(t, 2, cap(*a), "Storage should be kept")
+}
+
+func TestParseInto(t *testing.T) {
+    first := NewBinson().
+        Put("a", NewBinson().
+            Put("b", 1)).
+        Put("c", NewBinsonArray().
+            Put(NewBinson().
+                Put("d", "e"))).
+        Put("f", "g").
+        ToBytes()
+    second := NewBinson().
+        Put("x", NewBinson().
+            Put("y", NewBinsonArray().
+                Put(2))).
+        Put("z", NewBinson()).
+        ToBytes()
+
+    dst := NewBinson().
+        Put("old", 1)
+    assert.Nil(t, ParseInto(dst, first), "Got error")
+    assert.Equal(t, first, dst.ToBytes(), "Bytes do not match")
+    assert.Nil(t, ParseInto(dst, second), "Got error")
+    assert.Equal(t, second, dst.ToBytes(), "Bytes do not match")
+    assert.Nil(t, ParseInto(dst, first), "Got error")
+    assert.Equal(t, first, dst.ToBytes(), "Bytes do not match")
+
+    dec := NewDecoder()
+    shared := NewBinson().
+        Put("s", 1)
+    dst = NewBinson().
+        Put("a", shared).
+        Put("b", shared)
+    assert.Nil(t, dec.ParseInto(dst, second), "Got error")
+    assert.Equal(t, second, dst.ToBytes(), "Bytes do not match")
+    assert.Nil(t, dec.ParseInto(dst, first), "Got error")
+    assert.Equal(t, first, dst.ToBytes(), "Bytes do not match")
+
+    assert.NotNil(t, ParseInto(dst, []byte{0x42, 0x43}), "Should get error")
+    assert.NotNil(t, ParseInto(dst, []byte{}), "Should get error")
+    assert.NotNil(t, ParseInto(nil, first), "Should get error")
+}
+
+func BenchmarkParseInto(b *testing.B) {
+    corpus := benchmarkCorpus()
+    dec := NewDecoder()
+    dst := NewBinson()
+    b.ReportAllocs()
+    for i := 0; i < b.N; i++ {
+        for _, data := range corpus {
+            if err := dec.ParseInto(dst, data); err != nil {
+                b.Fatal(err)
+            }
+        }
+    }
+}
+
+func TestParseArrayValue(t *testing.T) {
+    data, _ := hex.DecodeString("421004140467696769404142431803010203454614ae47e17a543e4043")
+    a, err := ParseArray(data)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, data, a.ToBytes(), "Bytes do not match")
+    _, err = ParseArray([]byte{0x40, 0x41})
+    assert.NotNil(t, err, "Should get error")
+    _, err = ParseArray([]byte{0x42, 0x10})
+    assert.NotNil(t, err, "Should get error")
+    _, err = ParseArray([]byte{})
+    assert.NotNil(t, err, "Should get error")
+
+    view, _ := NewArrayView(data)
+    a, err = view.ToArray()
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, data, a.ToBytes(), "Bytes do not match")
+
+    for _, value := range []interface{}{NewBinson().Put("a", 1), a, 300, "text", []byte{1}, true, 2.5} {
+        v, _ := ValueOf(value)
+        back, err := ParseValue(v.ToBytes())
+        assert.Nil(t, err, "Got error")
+        assert.Equal(t, v.Kind(), back.Kind(), "Wrong kind")
+        assert.Equal(t, v.ToBytes(), back.ToBytes(), "Bytes do not match")
+    }
+    _, err = ParseValue([]byte{0x11, 0x01})
+    assert.NotNil(t, err, "Should get error")
+    _, err = ParseValue([]byte{0x41})
+    assert.NotNil(t, err, "Should get error")
+    _, err = ParseValue(nil)
+    assert.NotNil(t, err, "Should get error")
+}
+
+type oneByteReader struct {
+    data []byte
+}
+
+func (r *oneByteReader) Read(p []byte) (int, error) {
+    if len(r.data) == 0 {
+        return 0, io.EOF
+    }
+    p[0] = r.data[0]
+    r.data = r.data[1:]
+    return 1, nil
+}
+
+func TestScanner(t *testing.T) {
+    var stream []byte
+    offsets := []int64{}
+    for i := 0; i < 50; i++ {
+        offsets = append(offsets, int64(len(stream)))
+        stream = NewBinson().
+            Put("i", i).
+            Put("data", make([]byte, i * 100)).
+            AppendBinson(stream)
+    }
+
+    for _, r := range []io.Reader{bytes.NewReader(stream), &oneByteReader{stream}} {
+        s := NewScanner(r)
+        count := 0
+        for s.Scan() {
+            n, _ := s.Binson().GetInt("i")
+            assert.Equal(t, int64(count), n, "Wrong value")
+            assert.Equal(t, offsets[count], s.Offset(), "Wrong offset")
+            assert.Equal(t, s.Binson().ToBytes(), s.Bytes(), "Bytes do not match")
+            count++
+        }
+        assert.Nil(t, s.Err(), "Got error")
+        assert.Equal(t, 50, count, "Wrong count")
+    }
+
+    s := NewScanner(bytes.NewReader(nil))
+    assert.False(t, s.Scan(), "Should be empty")
+    assert.Nil(t, s.Err(), "Got error")
+
+    nested := NewBinson().
+        Put("a", NewBinsonArray().
+            Put(NewBinson().Put("b", NewBinsonArray().Put(1).Put("x"))).
+            Put(NewBinsonArray())).
+        Put("c", NewBinson())
+    data := append(nested.ToBytes(), nested.ToBytes()...)
+    s = NewScanner(&oneByteReader{data})
+    for i := 0; i < 2; i++ {
+        assert.True(t, s.Scan(), "Should read object")
+        assert.Equal(t, nested.ToBytes(), s.Bytes(), "Bytes do not match")
+    }
+    assert.False(t, s.Scan(), "Should end")
+    assert.Nil(t, s.Err(), "Got error")
+}
+
+// chunkReader returns at most size bytes per Read, like a pipe or socket.
+type chunkReader struct {
+    data []byte
+    size int
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+    if len(r.data) == 0 {
+        return 0, io.EOF
+    }
+    n := copy(p[:min(len(p), r.size)], r.data)
+    r.data = r.data[n:]
+    return n, nil
+}
+
+func BenchmarkScannerChunked(b *testing.B) {
+    a := NewBinsonArray()
+    for i := 0; i < 200000; i++ {
+        a.Put(NewBinson().Put("i", i))
+    }
+    data := NewBinson().Put("a", a).ToBytes()
+    b.SetBytes(int64(len(data)))
+    for i := 0; i < b.N; i++ {
+        s := NewScanner(&chunkReader{data, 4096})
+        if !s.Scan() {
+            b.Fatal(s.Err())
+        }
+    }
+}
+
+func TestScannerCorrupt(t *testing.T) {
+    first := NewBinson().Put("a", 1).ToBytes()
+    second := NewBinson().Put("b", 2).ToBytes()
+    var stream []byte
+    stream = append(stream, first...)
+    stream = append(stream, 0x40, 0x99, 0x00)
+    stream = append(stream, second...)
+    stream = append(stream, first[:4]...)
+
+    s := NewScanner(bytes.NewReader(stream))
+    assert.True(t, s.Scan(), "Should read object")
+    assert.False(t, s.Scan(), "Should stop at corrupt object")
+    assert.Nil(t, s.Binson(), "Should not have object")
+    assert.Contains(t, s.Err().Error(), "offset 7", "Wrong error")
+
+    s = NewScanner(bytes.NewReader(stream))
+    s.SetResync(true)
+    names := []string{}
+    for s.Scan() {
+        names = append(names, s.Binson().FieldNames()...)
+    }
+    assert.Nil(t, s.Err(), "Got error")
+    assert.Equal(t, []string{"a", "b"}, names, "Keys do not match")
+    assert.Equal(t, int64(7), s.Skipped(), "Wrong skipped count")
+
+    s = NewScanner(bytes.NewReader(NewBinson().Put("a", make([]byte, 10000)).ToBytes()))
+    s.SetMaxObjectSize(5000)
+    assert.False(t, s.Scan(), "Should not read object")
+    assert.True(t, errors.Is(s.Err(), ErrTooLarge), "Wrong error")
+}
+
+func TestLookup(t *testing.T) {
+    b := NewBinson().
+        Put("a", NewBinsonArray().
+            Put(1).
+            Put(NewBinson().
+                Put("b", "c")))
+    v, ok := Lookup(b, Path{"a", 1, "b"})
+    assert.True(t, ok, "Should have object")
+    so, _ := v.GetString()
+    assert.Equal(t, "c", so, "Wrong value")
+    v, ok = Lookup(b, Path{})
+    assert.True(t, ok, "Should have object")
+    assert.Equal(t, BinsonKind, v.Kind(), "Wrong kind")
+    for _, path := range []Path{{"x"}, {"a", 2}, {"a", "b"}, {"a", 0, "b"}, {"a", 1.5}} {
+        _, ok = Lookup(b, path)
+        assert.False(t, ok, "Should not have object at %v", path)
+    }
+}
+
+func TestMerkle(t *testing.T) {
+    b := NewBinson().
+        Put("name", "alice").
+        Put("secret", "hidden").
+        Put("roles", NewBinsonArray().
+            Put("admin").
+            Put(NewBinson().
+                Put("scope", "billing"))).
+        Put("meta", NewBinson().
+            Put("age", 30))
+    root := MerkleRoot(b)
+    other := NewBinson().
+        Put("meta", NewBinson().Put("age", 30)).
+        Put("roles", NewBinsonArray().Put("admin").Put(NewBinson().Put("scope", "billing"))).
+        Put("secret", "hidden").
+        Put("name", "alice")
+    assert.Equal(t, root, MerkleRoot(other), "Equal objects shall have equal roots")
+    other.Put("secret", "changed")
+    assert.NotEqual(t, root, MerkleRoot(other), "Roots should differ")
+
+    cases := []struct {
+        path Path
+        value interface{}
+    }{
+        {Path{"name"}, "alice"},
+        {Path{"meta", "age"}, 30},
+        {Path{"meta"}, NewBinson().Put("age", 30)},
+        {Path{"roles", 0}, "admin"},
+        {Path{"roles", 1, "scope"}, "billing"},
+        {Path{}, b},
+    }
+    for _, c := range cases {
+        proof, err := Prove(b, c.path)
+        assert.Nil(t, err, "Got error")
+        assert.True(t, VerifyProof(root, c.path, c.value, proof), "Should verify %s", c.path)
+        parsed, err := ParseProof(proof.ToBytes())
+        assert.Nil(t, err, "Got error")
+        assert.True(t, VerifyProof(root, c.path, c.value, parsed), "Should verify %s", c.path)
+        assert.False(t, VerifyProof(root, c.path, "wrong", proof), "Should not verify %s", c.path)
+    }
+
+    proof, _ := Prove(b, Path{"name"})
+    assert.False(t, VerifyProof(root, Path{"secret"}, "alice", proof), "Should not verify other path")
+    assert.False(t, VerifyProof(root, Path{"name", "x"}, "alice", proof), "Should not verify other path")
+    proof.Levels[0].Siblings[0][0] ^= 1
+    assert.False(t, VerifyProof(root, Path{"name"}, "alice", proof), "Should not verify tampered proof")
+    proof.Levels[0].Position = 9
+    assert.False(t, VerifyProof(root, Path{"name"}, "alice", proof), "Should not verify bad position")
+
+    for _, path := range []Path{{"x"}, {"name", "x"}, {"roles", 2}, {"roles", "x"}, {1.5}} {
+        _, err := Prove(b, path)
+        assert.NotNil(t, err, "Should get error for %v", path)
+    }
+    _, err := ParseProof([]byte{0x40, 0x41})
+    assert.NotNil(t, err, "Should get error")
+}
+
+func TestClone(t *testing.T) {
+    b := NewBinson().
+        Put("a", NewBinson().
+            Put("b", []byte{1})).
+        Put("c", NewBinsonArray().
+            Put(NewBinson()))
+    c := b.Clone()
+    assert.Equal(t, b.ToBytes(), c.ToBytes(), "Bytes do not match")
+    inner, _ := c.GetBinson("a")
+    inner.Put("x", 1)
+    yo, _ := inner.GetBytes("b")
+    yo[0] = 9
+    arr, _ := c.GetArray("c")
+    arr.Put(2)
+    want, _ := hex.DecodeString("4014016140140162180101411401634240414341")
+    assert.Equal(t, want, b.ToBytes(), "Original should be unchanged")
+}
+
+func TestReplaceRedact(t *testing.T) {
+    b := NewBinson().
+        Put("user", "alice").
+        Put("token", "secret").
+        Put("cards", NewBinsonArray().
+            Put(NewBinson().
+                Put("number", "4111")))
+    assert.Nil(t, Replace(b, Path{"cards", 0, "number"}, "4222"), "Got error")
+    v, _ := Lookup(b, Path{"cards", 0, "number"})
+    so, _ := v.GetString()
+    assert.Equal(t, "4222", so, "Wrong value")
+    assert.Nil(t, Replace(b, Path{"cards", 0}, 1), "Got error")
+    for _, path := range []Path{{}, {"x"}, {"x", "y"}, {"user", "y"}, {"cards", 1}, {"cards", "y"}, {"user", 1.5}} {
+        assert.NotNil(t, Replace(b, path, 1), "Should get error for %v", path)
+    }
+
+    r := Redact(b, []Path{{"token"}, {"cards", 0}, {"missing"}, {}})
+    so, _ = r.GetString("token")
+    assert.Equal(t, RedactedMarker, so, "Should be redacted")
+    so, _ = r.GetString("user")
+    assert.Equal(t, "alice", so, "Should not be redacted")
+    arr, _ := r.GetArray("cards")
+    so, _ = arr.GetString(0)
+    assert.Equal(t, RedactedMarker, so, "Should be redacted")
+    so, _ = b.GetString("token")
+    assert.Equal(t, "secret", so, "Original should be unchanged")
+}
+
+func TestArmor(t *testing.T) {
+    b := NewBinson().
+        Put("kid", "k1").
+        Put("key", []byte{1, 2, 3, 250})
+    text := Armor(b, "BINSON KEY")
+    assert.Contains(t, string(text), "-----BEGIN BINSON KEY-----", "Wrong label")
+    back, label, err := Dearmor(append([]byte("comment\n"), text...))
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, "BINSON KEY", label, "Wrong label")
+    assert.Equal(t, b.ToBytes(), back.ToBytes(), "Bytes do not match")
+
+    text, err = ArmorHeaders(b, "TOKEN", map[string]string{"Issuer": "test"})
+    assert.Nil(t, err, "Got error")
+    back, label, headers, err := DearmorHeaders(text)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, "TOKEN", label, "Wrong label")
+    assert.Equal(t, map[string]string{"Issuer": "test"}, headers, "Headers do not match")
+    assert.Equal(t, b.ToBytes(), back.ToBytes(), "Bytes do not match")
+
+    other := Armor(NewBinson().Put("kid", "k2").Put("key", []byte{1, 2, 3, 250}), "TOKEN")
+    lines := bytes.Split(text, []byte("\n"))
+    otherLines := bytes.Split(other, []byte("\n"))
+    lines[len(lines)-3] = otherLines[len(otherLines)-3]
+    _, _, err = Dearmor(bytes.Join(lines, []byte("\n")))
+    assert.NotNil(t, err, "Should get CRC error")
+    _, _, err = Dearmor([]byte("no block"))
+    assert.NotNil(t, err, "Should get error")
+    _, _, err = Dearmor([]byte("-----BEGIN X-----\nCrc32: zz\n\nQEE=\n-----END X-----\n"))
+    assert.NotNil(t, err, "Should get error")
+    back, _, err = Dearmor([]byte("-----BEGIN X-----\nQEE=\n-----END X-----\n"))
+    assert.Nil(t, err, "Block without CRC shall be accepted")
+    assert.Equal(t, NewBinson(), back, "Wrong value")
+
+    for _, bad := range []map[string]string{
+        {"Key:Id": "k1"},
+        {"Issuer": "line\nbreak"},
+        {"Crc32": "00000000"},
+    } {
+        text, err = ArmorHeaders(b, "TOKEN", bad)
+        assert.NotNil(t, err, "Should get error for %v", bad)
+        assert.Nil(t, text, "Should get no armor")
+    }
+}
+
+func TestEncodeString(t *testing.T) {
+    b := NewBinson().
+        Put("a", []byte{0xfb, 0xff})
+    s := EncodeString(b)
+    assert.Equal(t, "QBQBYRgC-_9B", s, "Wrong string")
+    back, err := DecodeString(s)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, b.ToBytes(), back.ToBytes(), "Bytes do not match")
+    _, err = DecodeString("QBQBYRgC+/9B")
+    assert.NotNil(t, err, "Should get error")
+}
@@ -0,0 +1,127 @@
+package binson
+
+import (
+    "errors"
+    "fmt"
+    "math"
+)
+
+// ErrOverflow is returned when a number does not fit in the requested type.
+var ErrOverflow = errors.New("Value out of range")
+
+// maxMarshalDepth limits how many Marshalers may return another Marshaler
+// in a row, so a cycle gives an error instead of a stack overflow.
+const maxMarshalDepth = 32
+
+// Marshaler is implemented by types that can map themselves to one of the
+// Binson value kinds. MarshalBinson shall return a Binson, *BinsonArray,
+// int64, string, []byte, bool or float64 (or another Marshaler, at most
+// 32 in a row).
+type Marshaler interface {
+    MarshalBinson() (interface{}, error)
+}
+
+// Unmarshaler is implemented by types that can restore themselves from a
+// Binson value. The value is given as Binson, *BinsonArray, int64, string,
+// []byte, bool or float64.
+type Unmarshaler interface {
+    UnmarshalBinson(value interface{}) error
+}
+
+// Converts a Go value to a Binson field.
+func toField(value interface{}) (field, error) {
+    return toFieldDepth(value, 0)
+}
+
+func toFieldDepth(value interface{}, depth int) (field, error) {
+    switch o := value.(type) {
+        case Binson:
+            return o, nil
+        case *BinsonArray:
+            return o, nil
+        case int:
+            return binsonInt(int64(o)), nil
+        case int8:
+            return binsonInt(int64(o)), nil
+        case int16:
+            return binsonInt(int64(o)), nil
+        case int32:
+            return binsonInt(int64(o)), nil
+        case int64:
+            return binsonInt(o), nil
+        case uint:
+            return toFieldDepth(uint64(o), depth)
+        case uint8:
+            return binsonInt(int64(o)), nil
+        case uint16:
+            return binsonInt(int64(o)), nil
+        case uint32:
+            return binsonInt(int64(o)), nil
+        case uint64:
+            if o > math.MaxInt64 {
+                return nil, fmt.Errorf("%w: %d", ErrOverflow, o)
+            }
+            return binsonInt(int64(o)), nil
+        case string:
+            return binsonString(o), nil
+        case []byte:
+            return binsonBytes(o), nil
+        case bool:
+            return binsonBool(o), nil
+        case float32:
+            return binsonFloat(float64(o)), nil
+        case float64:
+            return binsonFloat(o), nil
+        case Value:
+            if o.f == nil {
+                return nil, fmt.Errorf("Invalid Value is not handeled by Binson")
+            }
+            return o.f, nil
+        case Marshaler:
+            if depth >= maxMarshalDepth {
+                return nil, fmt.Errorf("Marshal of %T nested more than %d times", o, maxMarshalDepth)
+            }
+            v, err := o.MarshalBinson()
+            if err != nil {
+                return nil, fmt.Errorf("Marshal of %T failed: %v", o, err)
+            }
+            return toFieldDepth(v, depth+1)
+        default:
+            return nil, fmt.Errorf("%T is not handeled by Binson", o)
+    }
+}
+
+// Converts a Binson field to the exported Go type it represents.
+func fieldValue(f field) interface{} {
+    switch o := f.(type) {
+        case binsonInt:
+            return int64(o)
+        case binsonString:
+            return string(o)
+        case binsonBytes:
+            return []byte(o)
+        case binsonBool:
+            return bool(o)
+        case binsonFloat:
+            return float64(o)
+        default:
+            return o
+    }
+}
+
+// Restores v from the field with the given name.
+func (b Binson) Unmarshal(name string, v Unmarshaler) error {
+    f, ok := b[binsonString(name)]
+    if !ok {
+        return fmt.Errorf("No field named: %s", name)
+    }
+    return v.UnmarshalBinson(fieldValue(f))
+}
+
+// Restores v from the element at the given index.
+func (a *BinsonArray) Unmarshal(index int, v Unmarshaler) error {
+    if a.inRange(index){
+        return indexError(index)
+    }
+    return v.UnmarshalBinson(fieldValue((*a)[index]))
+}
@@ -0,0 +1,225 @@
+package binson
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "fmt"
+)
+
+// Merkle hashing of Binson trees. Each field of an object, and each element
+// of an array, is a child hashed as
+//
+//     leaf:  SHA-256(0x00 || key || value bytes)
+//     inner: SHA-256(0x01 || key || node hash of the value)
+//
+// where key is the encoded field name, or the encoded index for arrays,
+// and objects and arrays are inner children. The node hash of an object or
+// array is SHA-256 of its begin byte followed by the hashes of its children
+// in canonical order. The root hash of an object is its node hash.
+
+const (
+    merkleLeaf byte = 0x00
+    merkleInner = 0x01
+)
+
+// MerkleHash is a hash in the Merkle tree of a Binson object.
+type MerkleHash [sha256.Size]byte
+
+// Proof shows the value at a path inside an object with a known root hash,
+// without revealing other values. It holds one level per path element,
+// starting at the root.
+type Proof struct {
+    Levels []ProofLevel
+}
+
+// ProofLevel holds the hashes of the children next to the one on the path.
+type ProofLevel struct {
+    Position int
+    Siblings []MerkleHash
+}
+
+func merkleChild(key field, value field) MerkleHash {
+    h := sha256.New()
+    switch o := value.(type) {
+        case Binson, *BinsonArray:
+            node := merkleNode(o)
+            h.Write([]byte{merkleInner})
+            h.Write(key.appendTo(nil))
+            h.Write(node[:])
+        default:
+            h.Write([]byte{merkleLeaf})
+            h.Write(key.appendTo(nil))
+            h.Write(value.appendTo(nil))
+    }
+    var sum MerkleHash
+    h.Sum(sum[:0])
+    return sum
+}
+
+func merkleChildren(f field) []MerkleHash {
+    switch o := f.(type) {
+        case Binson:
+            hashes := make([]MerkleHash, 0, len(o))
+            for _, key := range o.sortedKeys() {
+                hashes = append(hashes, merkleChild(binsonString(key), o[binsonString(key)]))
+            }
+            return hashes
+        case *BinsonArray:
+            hashes := make([]MerkleHash, 0, o.Size())
+            for i, value := range *o {
+                hashes = append(hashes, merkleChild(binsonInt(i), value))
+            }
+            return hashes
+        default:
+            return nil
+    }
+}
+
+func merkleCombine(start byte, children []MerkleHash) MerkleHash {
+    h := sha256.New()
+    h.Write([]byte{start})
+    for _, child := range children {
+        h.Write(child[:])
+    }
+    var sum MerkleHash
+    h.Sum(sum[:0])
+    return sum
+}
+
+func merkleNode(f field) MerkleHash {
+    start := binsonBegin
+    if _, ok := f.(*BinsonArray); ok {
+        start = binsonBeginArray
+    }
+    return merkleCombine(start, merkleChildren(f))
+}
+
+// Returns the Merkle root hash of a Binson object.
+func MerkleRoot(b Binson) MerkleHash {
+    return merkleNode(b)
+}
+
+// Returns a proof of the value at path inside b.
+func Prove(b Binson, path Path) (Proof, error) {
+    proof := Proof{}
+    var f field = b
+    for _, e := range path {
+        children := merkleChildren(f)
+        position := -1
+        switch o := e.(type) {
+            case string:
+                obj, ok := f.(Binson)
+                if !ok {
+                    return Proof{}, fmt.Errorf("No object at %s", path)
+                }
+                if f, ok = obj[binsonString(o)]; !ok {
+                    return Proof{}, fmt.Errorf("No value at %s", path)
+                }
+                for i, key := range obj.sortedKeys() {
+                    if key == o {
+                        position = i
+                    }
+                }
+            case int:
+                arr, ok := f.(*BinsonArray)
+                if !ok || arr.inRange(o) {
+                    return Proof{}, fmt.Errorf("No value at %s", path)
+                }
+                f = (*arr)[o]
+                position = o
+            default:
+                return Proof{}, fmt.Errorf("Bad path element: %T", e)
+        }
+        siblings := append(children[:position:position], children[position+1:]...)
+        proof.Levels = append(proof.Levels, ProofLevel{position, siblings})
+    }
+    return proof, nil
+}
+
+// Returns true if proof shows that the value at path inside the object with
+// the given root hash equals value. The value is given as for Put.
+func VerifyProof(root MerkleHash, path Path, value interface{}, proof Proof) bool {
+    f, err := toField(value)
+    if err != nil || len(path) != len(proof.Levels) {
+        return false
+    }
+    if len(path) == 0 {
+        _, ok := f.(Binson)
+        return ok && merkleNode(f) == root
+    }
+    var child MerkleHash
+    for i := len(path) - 1; i >= 0; i-- {
+        var key field
+        start := binsonBegin
+        switch o := path[i].(type) {
+            case string:
+                key = binsonString(o)
+            case int:
+                key = binsonInt(o)
+                start = binsonBeginArray
+            default:
+                return false
+        }
+        if i == len(path) - 1 {
+            child = merkleChild(key, f)
+        } else {
+            h := sha256.New()
+            h.Write([]byte{merkleInner})
+            h.Write(key.appendTo(nil))
+            h.Write(child[:])
+            h.Sum(child[:0])
+        }
+        level := proof.Levels[i]
+        if level.Position < 0 || len(level.Siblings) < level.Position {
+            return false
+        }
+        children := make([]MerkleHash, 0, len(level.Siblings) + 1)
+        children = append(children, level.Siblings[:level.Position]...)
+        children = append(children, child)
+        children = append(children, level.Siblings[level.Position:]...)
+        child = merkleCombine(start, children)
+    }
+    return child == root
+}
+
+// Writes the proof to bytes as a Binson object.
+func (p Proof) ToBytes() []byte {
+    levels := NewBinsonArray()
+    for _, level := range p.Levels {
+        var siblings bytes.Buffer
+        for _, h := range level.Siblings {
+            siblings.Write(h[:])
+        }
+        levels.Put(NewBinson().
+            Put("p", level.Position).
+            Put("s", siblings.Bytes()))
+    }
+    return NewBinson().Put("l", levels).ToBytes()
+}
+
+// Parses a proof written by Proof.ToBytes.
+func ParseProof(data []byte) (Proof, error) {
+    b, err := Parse(data)
+    if err != nil {
+        return Proof{}, err
+    }
+    levels, ok := b.GetArray("l")
+    if !ok {
+        return Proof{}, fmt.Errorf("Proof without levels")
+    }
+    proof := Proof{}
+    for i := 0; i < levels.Size(); i++ {
+        level, _ := levels.GetBinson(i)
+        position, ok1 := level.GetInt("p")
+        siblings, ok2 := level.GetBytes("s")
+        if !ok1 || !ok2 || len(siblings) % sha256.Size != 0 {
+            return Proof{}, fmt.Errorf("Bad proof level: %d", i)
+        }
+        hashes := make([]MerkleHash, len(siblings) / sha256.Size)
+        for j := range hashes {
+            copy(hashes[j][:], siblings[j*sha256.Size:])
+        }
+        proof.Levels = append(proof.Levels, ProofLevel{int(position), hashes})
+    }
+    return proof, nil
+}
@@ -0,0 +1,133 @@
+package binson
+
+import (
+    "math"
+)
+
+func checkRange(value int64, ok bool, min int64, max int64) (int64, bool) {
+    if !ok || value < min || max < value {
+        return 0, false
+    }
+    return value, true
+}
+
+func checkFloat32(value float64, ok bool) (float32, bool) {
+    if !ok || (math.Abs(value) > math.MaxFloat32 && !math.IsInf(value, 0)) {
+        return 0, false
+    }
+    return float32(value), true
+}
+
+// Returns the integer field with the given name as an int32. Reports false if
+// the field is missing, is not an integer or does not fit.
+func (b Binson) GetInt32(name string) (int32, bool) {
+    v, ok := b.GetInt(name)
+    v, ok = checkRange(v, ok, math.MinInt32, math.MaxInt32)
+    return int32(v), ok
+}
+
+func (b Binson) GetInt16(name string) (int16, bool) {
+    v, ok := b.GetInt(name)
+    v, ok = checkRange(v, ok, math.MinInt16, math.MaxInt16)
+    return int16(v), ok
+}
+
+func (b Binson) GetInt8(name string) (int8, bool) {
+    v, ok := b.GetInt(name)
+    v, ok = checkRange(v, ok, math.MinInt8, math.MaxInt8)
+    return int8(v), ok
+}
+
+func (b Binson) GetUint(name string) (uint, bool) {
+    v, ok := b.GetInt(name)
+    v, ok = checkRange(v, ok, 0, math.MaxInt64)
+    if uint64(v) > uint64(^uint(0)) {
+        return 0, false
+    }
+    return uint(v), ok
+}
+
+func (b Binson) GetUint64(name string) (uint64, bool) {
+    v, ok := b.GetInt(name)
+    v, ok = checkRange(v, ok, 0, math.MaxInt64)
+    return uint64(v), ok
+}
+
+func (b Binson) GetUint32(name string) (uint32, bool) {
+    v, ok := b.GetInt(name)
+    v, ok = checkRange(v, ok, 0, math.MaxUint32)
+    return uint32(v), ok
+}
+
+func (b Binson) GetUint16(name string) (uint16, bool) {
+    v, ok := b.GetInt(name)
+    v, ok = checkRange(v, ok, 0, math.MaxUint16)
+    return uint16(v), ok
+}
+
+func (b Binson) GetUint8(name string) (uint8, bool) {
+    v, ok := b.GetInt(name)
+    v, ok = checkRange(v, ok, 0, math.MaxUint8)
+    return uint8(v), ok
+}
+
+// Returns the float field with the given name as a float32. Reports false if
+// the field is missing, is not a float or is too large for a float32.
+func (b Binson) GetFloat32(name string) (float32, bool) {
+    return checkFloat32(b.GetFloat(name))
+}
+
+func (a *BinsonArray) GetInt32(index int) (int32, bool) {
+    v, ok := a.GetInt(index)
+    v, ok = checkRange(v, ok, math.MinInt32, math.MaxInt32)
+    return int32(v), ok
+}
+
+func (a *BinsonArray) GetInt16(index int) (int16, bool) {
+    v, ok := a.GetInt(index)
+    v, ok = checkRange(v, ok, math.MinInt16, math.MaxInt16)
+    return int16(v), ok
+}
+
+func (a *BinsonArray) GetInt8(index int) (int8, bool) {
+    v, ok := a.GetInt(index)
+    v, ok = checkRange(v, ok, math.MinInt8, math.MaxInt8)
+    return int8(v), ok
+}
+
+func (a *BinsonArray) GetUint(index int) (uint, bool) {
+    v, ok := a.GetInt(index)
+    v, ok = checkRange(v, ok, 0, math.MaxInt64)
+    if uint64(v) > uint64(^uint(0)) {
+        return 0, false
+    }
+    return uint(v), ok
+}
+
+func (a *BinsonArray) GetUint64(index int) (uint64, bool) {
+    v, ok := a.GetInt(index)
+    v, ok = checkRange(v, ok, 0, math.MaxInt64)
+    return uint64(v), ok
+}
+
+func (a *BinsonArray) GetUint32(index int) (uint32, bool) {
+    v, ok := a.GetInt(index)
+    v, ok = checkRange(v, ok, 0, math.MaxUint32)
+    return uint32(v), ok
+}
+
+func (a *BinsonArray) GetUint16(index int) (uint16, bool) {
+    v, ok := a.GetInt(index)
+    v, ok = checkRange(v, ok, 0, math.MaxUint16)
+    return uint16(v), ok
+}
+
+func (a *BinsonArray) GetUint8(index int) (uint8, bool) {
+    v, ok := a.GetInt(index)
+    v, ok = checkRange(v, ok, 0, math.MaxUint8)
+    return uint8(v), ok
+}
+
+func (a *BinsonArray) GetFloat32(index int) (float32, bool) {
+    return checkFloat32(a.GetFloat(index))
+}
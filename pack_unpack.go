@@ -1,10 +1,11 @@
 package binson
 
 import (
-    "bytes"
     "encoding/binary"
     "fmt"
+    "io"
     "math"
+    "sort"
 )
 
 const(
@@ -28,7 +29,8 @@ const(
 )
 
 type field interface {
-    toBytes() []byte
+    appendTo(dst []byte) []byte
+    encodedSize() int
 }
 
 type Binson map[binsonString]field
@@ -39,228 +41,286 @@ type binsonBytes []byte
 type binsonBool bool
 type binsonFloat float64
 
-func packInteger(value int64) []byte {
-    buf := new(bytes.Buffer)
+// Returns the number of bytes needed to store an integer.
+func integerSize(value int64) int {
     if math.MinInt8 <= value && value <= math.MaxInt8 {
-        binary.Write(buf, binary.LittleEndian, int8(value))
+        return 1
     } else if math.MinInt16 <= value && value <= math.MaxInt16 {
-        binary.Write(buf, binary.LittleEndian, int16(value))
+        return 2
     } else if math.MinInt32 <= value && value <= math.MaxInt32 {
-        binary.Write(buf, binary.LittleEndian, int32(value))
+        return 4
     } else {
-        binary.Write(buf, binary.LittleEndian, value)
+        return 8
     }
-    return buf.Bytes()
 }
 
-func (b Binson) toBytes() []byte {
-    var buf bytes.Buffer
-    buf.WriteByte(binsonBegin)
-    for _, key := range b.FieldNames(){
-        buf.Write(binsonString(key).toBytes())
-        buf.Write(b[binsonString(key)].toBytes())
+func appendInteger(dst []byte, value int64, size int) []byte {
+    switch size {
+        case 1:
+            return append(dst, byte(value))
+        case 2:
+            return binary.LittleEndian.AppendUint16(dst, uint16(value))
+        case 4:
+            return binary.LittleEndian.AppendUint32(dst, uint32(value))
+        default:
+            return binary.LittleEndian.AppendUint64(dst, uint64(value))
     }
-    buf.WriteByte(binsonEnd)
-    return buf.Bytes()
 }
 
-func (b *BinsonArray) ToBytes() []byte {
-    return b.toBytes()
+// Appends a string or bytes header with the type byte for the length size.
+func appendLength(dst []byte, length int, tag1 byte, tag2 byte, tag4 byte) []byte {
+    size := integerSize(int64(length))
+    switch size {
+        case 1:
+            dst = append(dst, tag1)
+        case 2:
+            dst = append(dst, tag2)
+        case 4:
+            dst = append(dst, tag4)
+        default:
+            panic(fmt.Sprintf("Can not handle byte array of size %d", size))
+    }
+    return appendInteger(dst, int64(length), size)
 }
 
-func (b *BinsonArray) toBytes() []byte {
-    var buf bytes.Buffer
-    buf.WriteByte(binsonBeginArray)
-    for _, field := range *b {
-        buf.Write(field.toBytes())
+func (b Binson) sortedKeys() []string {
+    keys := make([]string, 0, len(b))
+    for k := range b {
+        keys = append(keys, string(k))
     }
-    buf.WriteByte(binsonEndArray)
-    return buf.Bytes()
+    sort.Strings(keys)
+    return keys
 }
 
-func (a binsonInt) toBytes() []byte {
-    buf := new(bytes.Buffer)
-    packedInt := packInteger(int64(a))
-    switch len(packedInt) {
-        case 1:
-            buf.WriteByte(binsonInteger1)
-        case 2:
-            buf.WriteByte(binsonInteger2)
-        case 4:
-            buf.WriteByte(binsonInteger4)
-        case 8:
-            buf.WriteByte(binsonInteger8)
-        default: 
-            panic(fmt.Sprintf("Can not handle byte array of size %d", len(packedInt)))
+func (b Binson) appendTo(dst []byte) []byte {
+    dst = append(dst, binsonBegin)
+    for _, key := range b.sortedKeys() {
+        dst = binsonString(key).appendTo(dst)
+        dst = b[binsonString(key)].appendTo(dst)
     }
-    buf.Write(packedInt)
-    return buf.Bytes()
+    return append(dst, binsonEnd)
 }
 
-func (a binsonString) toBytes() []byte {
-    buf := new(bytes.Buffer)
-    lengtBytes := packInteger(int64(len(a)))
-    switch len(lengtBytes) {
-        case 1:
-            buf.WriteByte(binsonString1)
-        case 2:
-            buf.WriteByte(binsonString2)
-        case 4:
-            buf.WriteByte(binsonString4)
-        default: 
-            panic(fmt.Sprintf("Can not handle byte array of size %d", len(lengtBytes)))
+func (b Binson) encodedSize() int {
+    size := 2
+    for key, f := range b {
+        size += key.encodedSize() + f.encodedSize()
     }
-    buf.Write(lengtBytes)
-    buf.WriteString(string(a))
-    return buf.Bytes()
+    return size
 }
 
-func (a binsonBytes) toBytes() []byte {
-    buf := new(bytes.Buffer)
-    lengtBytes := packInteger(int64(len(a)))
-    switch len(lengtBytes) {
+func (b *BinsonArray) appendTo(dst []byte) []byte {
+    dst = append(dst, binsonBeginArray)
+    for _, field := range *b {
+        dst = field.appendTo(dst)
+    }
+    return append(dst, binsonEndArray)
+}
+
+func (b *BinsonArray) encodedSize() int {
+    size := 2
+    for _, field := range *b {
+        size += field.encodedSize()
+    }
+    return size
+}
+
+func (a binsonInt) appendTo(dst []byte) []byte {
+    size := integerSize(int64(a))
+    switch size {
         case 1:
-            buf.WriteByte(binsonBytes1)
+            dst = append(dst, binsonInteger1)
         case 2:
-            buf.WriteByte(binsonBytes2)
+            dst = append(dst, binsonInteger2)
         case 4:
-            buf.WriteByte(binsonBytes4)
-        default: 
-            panic(fmt.Sprintf("Can not handle byte array of size %d", len(lengtBytes)))
+            dst = append(dst, binsonInteger4)
+        default:
+            dst = append(dst, binsonInteger8)
     }
-    buf.Write(lengtBytes)
-    buf.Write(a)
-    return buf.Bytes()
+    return appendInteger(dst, int64(a), size)
+}
+
+func (a binsonInt) encodedSize() int {
+    return 1 + integerSize(int64(a))
+}
+
+func (a binsonString) appendTo(dst []byte) []byte {
+    dst = appendLength(dst, len(a), binsonString1, binsonString2, binsonString4)
+    return append(dst, a...)
+}
+
+func (a binsonString) encodedSize() int {
+    return 1 + integerSize(int64(len(a))) + len(a)
+}
+
+func (a binsonBytes) appendTo(dst []byte) []byte {
+    dst = appendLength(dst, len(a), binsonBytes1, binsonBytes2, binsonBytes4)
+    return append(dst, a...)
+}
+
+func (a binsonBytes) encodedSize() int {
+    return 1 + integerSize(int64(len(a))) + len(a)
 }
 
-func (a binsonBool) toBytes() []byte {
+func (a binsonBool) appendTo(dst []byte) []byte {
     if a {
-        return []byte{binsonTrue}
+        return append(dst, binsonTrue)
     } else {
-        return []byte{binsonFalse}
+        return append(dst, binsonFalse)
     }
 }
 
-func (a binsonFloat) toBytes() []byte {
-    buf := new(bytes.Buffer)
-    buf.WriteByte(binsonDouble)
-    binary.Write(buf, binary.LittleEndian, float64(a))
-    return buf.Bytes()
+func (a binsonBool) encodedSize() int {
+    return 1
 }
 
-func readInteger(prefix byte, buf *bytes.Buffer) (int64, error) {
-    switch prefix {
-        case binsonString1, binsonBytes1, binsonInteger1:
-            var value int8 
-            err := binary.Read(buf, binary.LittleEndian, &value)
-            return int64(value), err
+func (a binsonFloat) appendTo(dst []byte) []byte {
+    dst = append(dst, binsonDouble)
+    return binary.LittleEndian.AppendUint64(dst, math.Float64bits(float64(a)))
+}
 
-        case binsonString2, binsonBytes2, binsonInteger2:
-            var value int16
-            err := binary.Read(buf, binary.LittleEndian, &value)
-            return int64(value), err
+func (a binsonFloat) encodedSize() int {
+    return 9
+}
 
-        case binsonString4, binsonBytes4, binsonInteger4:
-            var value int32 
-            err := binary.Read(buf, binary.LittleEndian, &value)
-            return int64(value), err
+// decoder reads Binson values from a byte slice.
+type decoder struct {
+    data []byte
+    pos int
+    free *Decoder
+}
 
-        case binsonInteger8:
-            var value int64 
-            err := binary.Read(buf, binary.LittleEndian, &value)
-            return value, err
+func (d *decoder) readByte() (byte, error) {
+    if len(d.data) <= d.pos {
+        return 0, io.ErrUnexpectedEOF
+    }
+    next := d.data[d.pos]
+    d.pos++
+    return next, nil
+}
 
-        default:
-            panic(fmt.Sprintf("Unknown prefix: %X", prefix))
+func (d *decoder) readInteger(start byte) (int64, error) {
+    size := intSize(start)
+    value, ok := readInt(d.data, d.pos, size)
+    if !ok {
+        return 0, io.ErrUnexpectedEOF
     }
+    d.pos += size
+    return value, nil
 }
 
-func parseBinson(buf *bytes.Buffer) (Binson, error) {
-    b := NewBinson()
+// Returns the next length prefixed content. The slice aliases the input.
+func (d *decoder) readContent(start byte) ([]byte, error) {
+    length, err := d.readInteger(start)
+    if err != nil {
+        return nil, err
+    }
+    if length < 0 {
+        return nil, fmt.Errorf("Negative length: %d", length)
+    }
+    if int64(len(d.data) - d.pos) < length {
+        return nil, io.ErrUnexpectedEOF
+    }
+    data := d.data[d.pos:d.pos+int(length)]
+    d.pos += int(length)
+    return data, nil
+}
+
+func (d *decoder) parseBinson() (Binson, error) {
+    b := d.free.newBinson()
+    if err := d.parseBinsonInto(b); err != nil {
+        return nil, err
+    }
+    return b, nil
+}
+
+func (d *decoder) parseBinsonInto(b Binson) error {
     for {
-        next, err := buf.ReadByte()
+        next, err := d.readByte()
         if err != nil {
-            return nil, err
+            return err
         } else if next == binsonEnd {
-            return b, nil
+            return nil
         }
 
-        name, err := parseString(next, buf)
+        switch next {
+            case binsonString1, binsonString2, binsonString4:
+            default:
+                return fmt.Errorf("Expected field name, got: %X", next)
+        }
+        name, err := d.readContent(next)
         if err != nil {
-            return nil, err
+            return err
         }
-        next, err = buf.ReadByte()
+        next, err = d.readByte()
         if err != nil {
-            return nil, err
+            return err
         }
-        field, err1 := parseField(next, buf)
-        if err1 != nil {
-            return nil, err1
+        field, err := d.parseField(next)
+        if err != nil {
+            return err
         }
-        b.Put(name, field)
+        b[binsonString(name)] = field
     }
 }
 
-func parseArray(buf *bytes.Buffer) (*BinsonArray, error) {
-    a := NewBinsonArray()
+func (d *decoder) parseArray() (*BinsonArray, error) {
+    a := d.free.newArray()
     for {
-        next, errRead := buf.ReadByte()
-        if errRead != nil {
-            return nil, errRead
+        next, err := d.readByte()
+        if err != nil {
+            return nil, err
         } else if next == binsonEndArray {
             return a, nil
         }
 
-        field, errParse := parseField(next, buf)
-        if errParse != nil {
-            return nil, errParse
+        field, err := d.parseField(next)
+        if err != nil {
+            return nil, err
         }
-        a.Put(field)
+        a.addField(field)
     }
 }
 
-func parseString(start byte, buf *bytes.Buffer) (string, error) {
-    length, err := readInteger(start, buf)
-    data := buf.Next(int(length))
-    text := string(data)
-    return text, err
-}
-
-func parseBytes(start byte, buf *bytes.Buffer) ([]byte, error) {
-    length, err := readInteger(start, buf)
-    data := buf.Next(int(length))
-    return data, err
-}
-
-func parseInteger(start byte, buf *bytes.Buffer) (int64, error) {
-    value, err := readInteger(start, buf)
-    return value, err
-}
-
-func parseFloat(buf *bytes.Buffer) (float64, error) {
-    var value float64 
-    err := binary.Read(buf, binary.LittleEndian, &value)
-    return value, err
+func (d *decoder) parseFloat() (binsonFloat, error) {
+    if len(d.data) - d.pos < 8 {
+        return 0, io.ErrUnexpectedEOF
+    }
+    bits := binary.LittleEndian.Uint64(d.data[d.pos:])
+    d.pos += 8
+    return binsonFloat(math.Float64frombits(bits)), nil
 }
 
-func parseField(start byte, buf *bytes.Buffer) (interface{}, error) {
+func (d *decoder) parseField(start byte) (field, error) {
     switch start {
         case binsonBegin:
-            return parseBinson(buf)
+            return d.parseBinson()
         case binsonBeginArray:
-            return parseArray(buf)
+            return d.parseArray()
         case binsonString1, binsonString2, binsonString4:
-            return parseString(start, buf)
+            data, err := d.readContent(start)
+            if err != nil {
+                return nil, err
+            }
+            return binsonString(data), nil
         case binsonBytes1, binsonBytes2, binsonBytes4:
-            return parseBytes(start, buf)
+            data, err := d.readContent(start)
+            if err != nil {
+                return nil, err
+            }
+            return binsonBytes(append([]byte{}, data...)), nil
         case binsonInteger1, binsonInteger2, binsonInteger4, binsonInteger8:
-            return parseInteger(start, buf)
+            value, err := d.readInteger(start)
+            if err != nil {
+                return nil, err
+            }
+            return binsonInt(value), nil
         case binsonTrue:
-            return true, nil
+            return binsonBool(true), nil
         case binsonFalse:
-            return false, nil
+            return binsonBool(false), nil
         case binsonDouble:
-            return parseFloat(buf)
+            return d.parseFloat()
         default: 
             return nil, fmt.Errorf("Unknown byte: %X", start)
     }
@@ -268,20 +328,83 @@ func parseField(start byte, buf *bytes.Buffer) (interface{}, error) {
 
 // Parses bytes to a Binson object.
 func Parse(data []byte) (Binson, error) {
-    buf := bytes.NewBuffer(data)
-    start, err := buf.ReadByte()
+    d := decoder{data: data}
+    start, err := d.readByte()
     if err != nil {
         return nil, err
     }
-    field, err := parseField(start, buf)
-    binson, ok := field.(Binson)
-    if !ok {
-        return nil, fmt.Errorf("Got none Binson type: %T", field)
+    if start != binsonBegin {
+        return nil, fmt.Errorf("Got none Binson start: %X", start)
+    }
+    return d.parseBinson()
+}
+
+// Parses bytes to a Binson array.
+func ParseArray(data []byte) (*BinsonArray, error) {
+    d := decoder{data: data}
+    start, err := d.readByte()
+    if err != nil {
+        return nil, err
+    }
+    if start != binsonBeginArray {
+        return nil, fmt.Errorf("Got none array start: %X", start)
     }
-    return binson, err
+    return d.parseArray()
+}
+
+// Parses bytes to a single value of any kind.
+func ParseValue(data []byte) (Value, error) {
+    d := decoder{data: data}
+    start, err := d.readByte()
+    if err != nil {
+        return Value{}, err
+    }
+    f, err := d.parseField(start)
+    if err != nil {
+        return Value{}, err
+    }
+    return Value{f}, nil
 }
 
 // Writes this Binson object to bytes
 func (b Binson) ToBytes() []byte {
-    return b.toBytes()
+    return b.AppendBinson(make([]byte, 0, b.EncodedSize()))
+}
+
+// Appends the bytes of this Binson object to dst and returns the extended
+// buffer.
+func (b Binson) AppendBinson(dst []byte) []byte {
+    return b.appendTo(dst)
+}
+
+// Returns the number of bytes ToBytes would produce, without serializing.
+func (b Binson) EncodedSize() int {
+    return b.encodedSize()
+}
+
+// Writes the bytes of this Binson object to w.
+func (b Binson) WriteTo(w io.Writer) (int64, error) {
+    n, err := w.Write(b.ToBytes())
+    return int64(n), err
+}
+
+// Writes this array to bytes
+func (b *BinsonArray) ToBytes() []byte {
+    return b.AppendBinson(make([]byte, 0, b.EncodedSize()))
+}
+
+// Appends the bytes of this array to dst and returns the extended buffer.
+func (b *BinsonArray) AppendBinson(dst []byte) []byte {
+    return b.appendTo(dst)
+}
+
+// Returns the number of bytes ToBytes would produce, without serializing.
+func (b *BinsonArray) EncodedSize() int {
+    return b.encodedSize()
+}
+
+// Writes the bytes of this array to w.
+func (b *BinsonArray) WriteTo(w io.Writer) (int64, error) {
+    n, err := w.Write(b.ToBytes())
+    return int64(n), err
 }
\ No newline at end of file
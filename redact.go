@@ -0,0 +1,48 @@
+package binson
+
+import (
+    "fmt"
+)
+
+// RedactedMarker is the value Redact puts in place of redacted values.
+const RedactedMarker = "[redacted]"
+
+// Replaces the value at the given path below root. The path must lead to
+// an existing field or element. The value is given as for Put.
+func Replace(root Binson, path Path, value interface{}) error {
+    if len(path) == 0 {
+        return fmt.Errorf("Can not replace the root")
+    }
+    parent, ok := Lookup(root, path[:len(path)-1])
+    if !ok {
+        return fmt.Errorf("No value at %s", path)
+    }
+    switch o := path[len(path)-1].(type) {
+        case string:
+            b, ok := parent.GetBinson()
+            if !ok || !b.ContainsKey(o) {
+                return fmt.Errorf("No value at %s", path)
+            }
+            return b.TryPut(o, value)
+        case int:
+            a, ok := parent.GetArray()
+            if !ok {
+                return fmt.Errorf("No value at %s", path)
+            }
+            return a.Set(o, value)
+        default:
+            return fmt.Errorf("Bad path element: %T", o)
+    }
+}
+
+// Returns a copy of b where the values at the given paths are replaced by
+// RedactedMarker, for logging. Paths that do not exist are ignored.
+func Redact(b Binson, paths []Path) Binson {
+    c := b.Clone()
+    for _, path := range paths {
+        if _, ok := Lookup(c, path); ok && len(path) > 0 {
+            Replace(c, path, RedactedMarker)
+        }
+    }
+    return c
+}
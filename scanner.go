@@ -0,0 +1,202 @@
+package binson
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "io"
+)
+
+// DefaultMaxObjectSize is the default limit of a single object read by a
+// Scanner.
+const DefaultMaxObjectSize = 64 * 1024 * 1024
+
+const scannerReadSize = 4096
+
+// ErrTooLarge is returned when an object is larger than the size limit.
+var ErrTooLarge = errors.New("Object too large")
+
+// Scanner reads a stream of Binson objects stored back to back, such as a
+// file of concatenated ToBytes output.
+type Scanner struct {
+    r io.Reader
+    buf []byte
+    start int
+    base int64
+    eof bool
+    obj Binson
+    raw []byte
+    offset int64
+    err error
+    resync bool
+    maxSize int
+    skipped int64
+    // Walk state of a partly read object, kept between reads so data is
+    // not walked again from the object start.
+    pos int
+    depth int
+}
+
+// Returns a new Scanner reading from r.
+func NewScanner(r io.Reader) *Scanner {
+    return &Scanner{r: r, maxSize: DefaultMaxObjectSize}
+}
+
+// Sets if the Scanner shall skip corrupt data and continue at the next
+// possible object start instead of stopping with an error.
+func (s *Scanner) SetResync(resync bool) {
+    s.resync = resync
+}
+
+// Sets the largest object size the Scanner accepts.
+func (s *Scanner) SetMaxObjectSize(size int) {
+    s.maxSize = size
+}
+
+// Reads the next object. Returns false at the end of the stream or on an
+// error, see Err.
+func (s *Scanner) Scan() bool {
+    s.obj = nil
+    s.raw = nil
+    for s.err == nil {
+        data := s.buf[s.start:]
+        if len(data) == 0 {
+            if s.eof {
+                return false
+            }
+            s.fill()
+            continue
+        }
+        if data[0] != binsonBegin {
+            s.corrupt(fmt.Errorf("Got none Binson start: %X", data[0]))
+            continue
+        }
+        end, err := s.objectEnd(data)
+        if err == io.ErrUnexpectedEOF && !s.eof {
+            if len(data) < s.maxSize {
+                s.fill()
+                continue
+            }
+            err = ErrTooLarge
+        }
+        if err == nil && s.maxSize < end {
+            err = ErrTooLarge
+        }
+        if err != nil {
+            s.corrupt(err)
+            continue
+        }
+        obj, err := Parse(data[:end])
+        if err != nil {
+            s.corrupt(err)
+            continue
+        }
+        s.obj = obj
+        s.raw = data[:end:end]
+        s.offset = s.base + int64(s.start)
+        s.start += end
+        return true
+    }
+    return false
+}
+
+// Returns the end of the object at the start of data. The walk goes value by
+// value from the state of the last call, so reading a large object in small
+// pieces takes linear time. The structure of the object is checked when it
+// is parsed.
+func (s *Scanner) objectEnd(data []byte) (int, error) {
+    for {
+        if len(data) <= s.pos {
+            return 0, io.ErrUnexpectedEOF
+        }
+        switch data[s.pos] {
+            case binsonBegin, binsonBeginArray:
+                s.depth++
+                s.pos++
+            case binsonEnd, binsonEndArray:
+                s.depth--
+                s.pos++
+                if s.depth <= 0 {
+                    end := s.pos
+                    s.pos, s.depth = 0, 0
+                    return end, nil
+                }
+            default:
+                end, err := skipValue(data, s.pos)
+                if err != nil {
+                    return 0, err
+                }
+                s.pos = end
+        }
+    }
+}
+
+// Handles corrupt data at the current position, either by stopping with an
+// error or by skipping to the next possible object start.
+func (s *Scanner) corrupt(err error) {
+    s.pos, s.depth = 0, 0
+    offset := s.base + int64(s.start)
+    if !s.resync {
+        s.err = fmt.Errorf("Corrupt object at offset %d: %w", offset, err)
+        return
+    }
+    data := s.buf[s.start:]
+    next := bytes.IndexByte(data[1:], binsonBegin)
+    if next < 0 {
+        s.skipped += int64(len(data))
+        s.start = len(s.buf)
+    } else {
+        s.skipped += int64(next + 1)
+        s.start += next + 1
+    }
+}
+
+// Reads more data into the buffer, dropping data already scanned.
+func (s *Scanner) fill() {
+    if s.start > 0 {
+        n := copy(s.buf, s.buf[s.start:])
+        s.base += int64(s.start)
+        s.buf = s.buf[:n]
+        s.start = 0
+    }
+    if cap(s.buf) - len(s.buf) < scannerReadSize {
+        buf := make([]byte, len(s.buf), 2*cap(s.buf) + scannerReadSize)
+        copy(buf, s.buf)
+        s.buf = buf
+    }
+    n, err := s.r.Read(s.buf[len(s.buf):cap(s.buf)])
+    s.buf = s.buf[:len(s.buf)+n]
+    if err == io.EOF {
+        s.eof = true
+    } else if err != nil {
+        s.err = err
+    }
+}
+
+// Returns the object read by the last call to Scan.
+func (s *Scanner) Binson() Binson {
+    return s.obj
+}
+
+// Returns the bytes of the object read by the last call to Scan. The slice
+// is only valid until the next call to Scan.
+func (s *Scanner) Bytes() []byte {
+    return s.raw
+}
+
+// Returns the byte offset in the stream of the object read by the last
+// call to Scan.
+func (s *Scanner) Offset() int64 {
+    return s.offset
+}
+
+// Returns the number of bytes skipped as corrupt when resyncing.
+func (s *Scanner) Skipped() int64 {
+    return s.skipped
+}
+
+// Returns the first error that stopped the Scanner, or nil at a clean end
+// of the stream.
+func (s *Scanner) Err() error {
+    return s.err
+}
@@ -0,0 +1,105 @@
+package seal
+
+import (
+    "crypto/rand"
+    "fmt"
+
+    "github.com/hakanols/binson-go"
+)
+
+// Field blobs are bytes values of the form
+//
+//     version || kind || len(key id) || key id || nonce || ciphertext
+//
+// where the plaintext is the encoded original value and kind is its
+// binson.Kind, so decryption restores the original kind. The blob header
+// and the path of the field are authenticated as associated data, so a blob
+// can not be moved to another field. The path is bound as the encoded
+// Binson array of its names and indexes, since the string form of a path is
+// ambiguous for names containing '.', '[' or ']'.
+
+const fieldVersion byte = 1
+
+func fieldAD(header []byte, path binson.Path) []byte {
+    elements := binson.NewBinsonArray()
+    for _, e := range path {
+        elements.Put(e)
+    }
+    return elements.AppendBinson(append([]byte{}, header...))
+}
+
+// Returns a copy of b where the values at the given paths are replaced by
+// encrypted bytes blobs. Paths that do not exist are ignored.
+func EncryptFields(b binson.Binson, key Key, paths []binson.Path) (binson.Binson, error) {
+    aead, err := newAEAD(key)
+    if err != nil {
+        return nil, err
+    }
+    if len(key.ID) > 255 {
+        return nil, fmt.Errorf("Key id too long: %d", len(key.ID))
+    }
+    c := b.Clone()
+    for _, path := range paths {
+        v, ok := binson.Lookup(c, path)
+        if !ok || len(path) == 0 {
+            continue
+        }
+        header := []byte{fieldVersion, byte(v.Kind()), byte(len(key.ID))}
+        header = append(header, key.ID...)
+        nonce := make([]byte, aead.NonceSize())
+        if _, err := rand.Read(nonce); err != nil {
+            return nil, err
+        }
+        blob := append(header, nonce...)
+        blob = aead.Seal(blob, nonce, v.ToBytes(), fieldAD(header, path))
+        if err := binson.Replace(c, path, blob); err != nil {
+            return nil, err
+        }
+    }
+    return c, nil
+}
+
+// Returns a copy of b where the encrypted blobs at the given paths are
+// replaced by their original values. Paths that do not exist are ignored.
+func DecryptFields(b binson.Binson, keys Keyring, paths []binson.Path) (binson.Binson, error) {
+    c := b.Clone()
+    for _, path := range paths {
+        v, ok := binson.Lookup(c, path)
+        if !ok || len(path) == 0 {
+            continue
+        }
+        blob, ok := v.GetBytes()
+        if !ok || len(blob) < 3 || blob[0] != fieldVersion || len(blob) < 3 + int(blob[2]) {
+            return nil, fmt.Errorf("%w: no encrypted field at %s", ErrMalformed, path)
+        }
+        header := blob[:3+int(blob[2])]
+        kid := string(header[3:])
+        key, ok := keys[kid]
+        if !ok {
+            return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+        }
+        aead, err := newAEAD(key)
+        if err != nil {
+            return nil, err
+        }
+        rest := blob[len(header):]
+        if len(rest) < aead.NonceSize() {
+            return nil, fmt.Errorf("%w: short field at %s", ErrMalformed, path)
+        }
+        plaintext, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], fieldAD(header, path))
+        if err != nil {
+            return nil, ErrAuth
+        }
+        value, err := binson.ParseValue(plaintext)
+        if err != nil {
+            return nil, err
+        }
+        if value.Kind() != binson.Kind(header[1]) {
+            return nil, fmt.Errorf("%w: kind mismatch at %s", ErrMalformed, path)
+        }
+        if err := binson.Replace(c, path, value); err != nil {
+            return nil, err
+        }
+    }
+    return c, nil
+}
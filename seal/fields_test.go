@@ -0,0 +1,67 @@
+package seal
+
+import (
+    "bytes"
+    "errors"
+    "testing"
+
+    "github.com/hakanols/binson-go"
+    "github.com/stretchr/testify/assert"
+)
+
+func TestEncryptFields(t *testing.T) {
+    key := Key{"f1", ChaCha20Poly1305, bytes.Repeat([]byte{3}, KeySize)}
+    b := binson.NewBinson().
+        Put("route", "billing").
+        Put("token", "secret-token").
+        Put("age", 42).
+        Put("card", binson.NewBinson().
+            Put("number", "4111")).
+        Put("tags", binson.NewBinsonArray().
+            Put(true))
+    paths := []binson.Path{{"token"}, {"age"}, {"card"}, {"tags", 0}, {"missing"}}
+
+    enc, err := EncryptFields(b, key, paths)
+    assert.Nil(t, err, "Got error")
+    assert.False(t, bytes.Contains(enc.ToBytes(), []byte("secret")), "Field shall be encrypted")
+    assert.False(t, bytes.Contains(enc.ToBytes(), []byte("4111")), "Field shall be encrypted")
+    assert.True(t, enc.HasBytes("token"), "Should be bytes")
+    assert.True(t, enc.HasBytes("card"), "Should be bytes")
+    route, _ := enc.GetString("route")
+    assert.Equal(t, "billing", route, "Routing field shall stay readable")
+    assert.True(t, b.HasString("token"), "Original should be unchanged")
+
+    dec, err := DecryptFields(enc, NewKeyring(key), paths)
+    assert.Nil(t, err, "Got error")
+    assert.Equal(t, b.ToBytes(), dec.ToBytes(), "Bytes do not match")
+
+    _, err = DecryptFields(enc, NewKeyring(), paths)
+    assert.True(t, errors.Is(err, ErrUnknownKey), "Should get error")
+    _, err = DecryptFields(enc, NewKeyring(key), []binson.Path{{"route"}})
+    assert.True(t, errors.Is(err, ErrMalformed), "Should get error")
+
+    token, _ := enc.GetBytes("token")
+    moved := enc.Clone().Put("age", token)
+    _, err = DecryptFields(moved, NewKeyring(key), []binson.Path{{"age"}})
+    assert.Equal(t, ErrAuth, err, "Moved blob shall fail")
+
+    tampered := enc.Clone()
+    blob, _ := tampered.GetBytes("token")
+    blob[1] = byte(binson.IntKind)
+    _, err = DecryptFields(tampered, NewKeyring(key), []binson.Path{{"token"}})
+    assert.Equal(t, ErrAuth, err, "Changed kind shall fail")
+
+    // Paths with the same string form shall not share associated data.
+    flat := binson.NewBinson().Put("a.b", "secret")
+    enc, err = EncryptFields(flat, key, []binson.Path{{"a.b"}})
+    assert.Nil(t, err, "Got error")
+    blob, _ = enc.GetBytes("a.b")
+    nested := binson.NewBinson().Put("a", binson.NewBinson().Put("b", blob))
+    _, err = DecryptFields(nested, NewKeyring(key), []binson.Path{{"a", "b"}})
+    assert.Equal(t, ErrAuth, err, "Moved blob shall fail")
+    enc, _ = EncryptFields(binson.NewBinson().Put("a[0]", "secret"), key, []binson.Path{{"a[0]"}})
+    blob, _ = enc.GetBytes("a[0]")
+    array := binson.NewBinson().Put("a", binson.NewBinsonArray().Put(blob))
+    _, err = DecryptFields(array, NewKeyring(key), []binson.Path{{"a", 0}})
+    assert.Equal(t, ErrAuth, err, "Moved blob shall fail")
+}
@@ -0,0 +1,172 @@
+// Package seal wraps Binson objects in authenticated encryption envelopes.
+//
+// A sealed envelope is a Binson object
+//
+//     {"alg": algorithm, "kid": key id, "n": nonce, "h": header, "c": ciphertext}
+//
+// where header holds selected fields of the original object in plaintext,
+// so they can be read without the key, and ciphertext is the encryption of
+// the remaining fields. The algorithm, key id and header are authenticated
+// as associated data, so changing any of them makes Open fail.
+package seal
+
+import (
+    "crypto/aes"
+    "crypto/cipher"
+    "crypto/rand"
+    "errors"
+    "fmt"
+
+    "github.com/hakanols/binson-go"
+    "golang.org/x/crypto/chacha20poly1305"
+)
+
+// Algorithm names an AEAD cipher.
+type Algorithm string
+
+const (
+    // AES256GCM is AES-256 in Galois/Counter Mode.
+    AES256GCM Algorithm = "A256GCM"
+    // ChaCha20Poly1305 is ChaCha20-Poly1305 as in RFC 8439.
+    ChaCha20Poly1305 Algorithm = "C20P1305"
+)
+
+// KeySize is the size in bytes of keys for all algorithms.
+const KeySize = 32
+
+var (
+    // ErrUnknownKey is returned when opening an envelope for a key not given.
+    ErrUnknownKey = errors.New("Unknown key")
+    // ErrAlgorithm is returned for unsupported or mismatching algorithms.
+    ErrAlgorithm = errors.New("Unsupported algorithm")
+    // ErrMalformed is returned when an envelope is missing fields.
+    ErrMalformed = errors.New("Malformed envelope")
+    // ErrAuth is returned when an envelope fails authentication.
+    ErrAuth = errors.New("Message authentication failed")
+)
+
+// Key is a secret key with an id and the algorithm it is used with.
+type Key struct {
+    ID string
+    Algorithm Algorithm
+    Secret []byte
+}
+
+// Keyring holds keys by id.
+type Keyring map[string]Key
+
+// Returns a Keyring with the given keys.
+func NewKeyring(keys ...Key) Keyring {
+    ring := make(Keyring, len(keys))
+    for _, key := range keys {
+        ring[key.ID] = key
+    }
+    return ring
+}
+
+func newAEAD(key Key) (cipher.AEAD, error) {
+    if len(key.Secret) != KeySize {
+        return nil, fmt.Errorf("Wrong key size: %d", len(key.Secret))
+    }
+    switch key.Algorithm {
+        case AES256GCM:
+            block, err := aes.NewCipher(key.Secret)
+            if err != nil {
+                return nil, err
+            }
+            return cipher.NewGCM(block)
+        case ChaCha20Poly1305:
+            return chacha20poly1305.New(key.Secret)
+        default:
+            return nil, fmt.Errorf("%w: %s", ErrAlgorithm, key.Algorithm)
+    }
+}
+
+func associatedData(alg string, kid string, header binson.Binson) []byte {
+    return binson.NewBinson().
+        Put("alg", alg).
+        Put("kid", kid).
+        Put("h", header).
+        ToBytes()
+}
+
+// Encrypts b into an envelope. The fields named in headerFields are moved
+// to the plaintext header; names not in b are ignored.
+func Seal(b binson.Binson, key Key, headerFields []string) (binson.Binson, error) {
+    aead, err := newAEAD(key)
+    if err != nil {
+        return nil, err
+    }
+    header := binson.NewBinson()
+    body := binson.NewBinson()
+    for name, value := range b.All() {
+        body.Put(name, value)
+    }
+    for _, name := range headerFields {
+        if value, ok := body.Get(name); ok {
+            header.Put(name, value)
+            body.Remove(name)
+        }
+    }
+    nonce := make([]byte, aead.NonceSize())
+    if _, err := rand.Read(nonce); err != nil {
+        return nil, err
+    }
+    ad := associatedData(string(key.Algorithm), key.ID, header)
+    return binson.NewBinson().
+        Put("alg", string(key.Algorithm)).
+        Put("kid", key.ID).
+        Put("n", nonce).
+        Put("h", header).
+        Put("c", aead.Seal(nil, nonce, body.ToBytes(), ad)), nil
+}
+
+// Returns the key id of an envelope.
+func KeyID(env binson.Binson) (string, bool) {
+    return env.GetString("kid")
+}
+
+// Returns the plaintext header of an envelope. The header is not
+// authenticated until the envelope is opened.
+func Header(env binson.Binson) (binson.Binson, bool) {
+    return env.GetBinson("h")
+}
+
+// Decrypts an envelope with the key named by it and returns the original
+// object, with the header fields restored.
+func Open(env binson.Binson, keys Keyring) (binson.Binson, error) {
+    alg, ok1 := env.GetString("alg")
+    kid, ok2 := env.GetString("kid")
+    nonce, ok3 := env.GetBytes("n")
+    header, ok4 := env.GetBinson("h")
+    ciphertext, ok5 := env.GetBytes("c")
+    if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 {
+        return nil, ErrMalformed
+    }
+    key, ok := keys[kid]
+    if !ok {
+        return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+    }
+    if string(key.Algorithm) != alg {
+        return nil, fmt.Errorf("%w: envelope uses %s, key %s", ErrAlgorithm, alg, key.Algorithm)
+    }
+    aead, err := newAEAD(key)
+    if err != nil {
+        return nil, err
+    }
+    if len(nonce) != aead.NonceSize() {
+        return nil, ErrMalformed
+    }
+    plaintext, err := aead.Open(nil, nonce, ciphertext, associatedData(alg, kid, header))
+    if err != nil {
+        return nil, ErrAuth
+    }
+    b, err := binson.Parse(plaintext)
+    if err != nil {
+        return nil, err
+    }
+    for name, value := range header.All() {
+        b.Put(name, value)
+    }
+    return b, nil
+}
@@ -0,0 +1,82 @@
+package seal
+
+import (
+    "bytes"
+    "errors"
+    "testing"
+
+    "github.com/hakanols/binson-go"
+    "github.com/stretchr/testify/assert"
+)
+
+func message() binson.Binson {
+    return binson.NewBinson().
+        Put("to", "billing").
+        Put("type", "invoice").
+        Put("amount", 120).
+        Put("card", binson.NewBinson().
+            Put("number", "4111111111111111"))
+}
+
+func TestSealOpen(t *testing.T) {
+    for _, alg := range []Algorithm{AES256GCM, ChaCha20Poly1305} {
+        key := Key{"k1", alg, bytes.Repeat([]byte{7}, KeySize)}
+        env, err := Seal(message(), key, []string{"to", "type", "missing"})
+        assert.Nil(t, err, "Got error")
+        assert.False(t, bytes.Contains(env.ToBytes(), []byte("4111")), "Body shall be encrypted")
+
+        header, ok := Header(env)
+        assert.True(t, ok, "Should have header")
+        assert.Equal(t, []string{"to", "type"}, header.FieldNames(), "Keys do not match")
+        kid, _ := KeyID(env)
+        assert.Equal(t, "k1", kid, "Wrong key id")
+
+        b, err := Open(env, NewKeyring(key))
+        assert.Nil(t, err, "Got error")
+        assert.Equal(t, message().ToBytes(), b.ToBytes(), "Bytes do not match")
+    }
+}
+
+func TestOpenFailures(t *testing.T) {
+    key := Key{"k1", AES256GCM, bytes.Repeat([]byte{1}, KeySize)}
+    ring := NewKeyring(key)
+    env, _ := Seal(message(), key, []string{"to"})
+
+    parse := func() binson.Binson {
+        b, _ := binson.Parse(env.ToBytes())
+        return b
+    }
+
+    changed := parse()
+    header, _ := changed.GetBinson("h")
+    header.Put("to", "attacker")
+    _, err := Open(changed, ring)
+    assert.Equal(t, ErrAuth, err, "Changed header shall fail")
+
+    changed = parse()
+    ciphertext, _ := changed.GetBytes("c")
+    ciphertext[0] ^= 1
+    _, err = Open(changed, ring)
+    assert.Equal(t, ErrAuth, err, "Changed ciphertext shall fail")
+
+    _, err = Open(env, NewKeyring(Key{"k2", AES256GCM, key.Secret}))
+    assert.True(t, errors.Is(err, ErrUnknownKey), "Should get error")
+    _, err = Open(env, NewKeyring(Key{"k1", ChaCha20Poly1305, key.Secret}))
+    assert.True(t, errors.Is(err, ErrAlgorithm), "Should get error")
+    _, err = Open(env, NewKeyring(Key{"k1", AES256GCM, bytes.Repeat([]byte{2}, KeySize)}))
+    assert.Equal(t, ErrAuth, err, "Wrong key shall fail")
+
+    changed = parse()
+    changed.Remove("n")
+    _, err = Open(changed, ring)
+    assert.Equal(t, ErrMalformed, err, "Should get error")
+    changed = parse()
+    changed.Put("n", []byte{1})
+    _, err = Open(changed, ring)
+    assert.Equal(t, ErrMalformed, err, "Should get error")
+
+    _, err = Seal(message(), Key{"k", "none", key.Secret}, nil)
+    assert.True(t, errors.Is(err, ErrAlgorithm), "Should get error")
+    _, err = Seal(message(), Key{"k", AES256GCM, []byte{1}}, nil)
+    assert.NotNil(t, err, "Should get error")
+}
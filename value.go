@@ -0,0 +1,140 @@
+package binson
+
+// Kind is the type of a Binson value.
+type Kind int
+
+const (
+    InvalidKind Kind = iota
+    BinsonKind
+    ArrayKind
+    IntKind
+    StringKind
+    BytesKind
+    BoolKind
+    FloatKind
+)
+
+var kindNames = []string{
+    "Invalid",
+    "Binson",
+    "Array",
+    "Int",
+    "String",
+    "Bytes",
+    "Bool",
+    "Float",
+}
+
+// Returns the name of the kind.
+func (k Kind) String() string {
+    if k < 0 || int(k) >= len(kindNames) {
+        return kindNames[InvalidKind]
+    }
+    return kindNames[k]
+}
+
+// Value is a single Binson value of any kind. The zero Value is invalid.
+type Value struct {
+    f field
+}
+
+func kindOf(f field) Kind {
+    switch f.(type) {
+        case Binson:
+            return BinsonKind
+        case *BinsonArray:
+            return ArrayKind
+        case binsonInt:
+            return IntKind
+        case binsonString:
+            return StringKind
+        case binsonBytes:
+            return BytesKind
+        case binsonBool:
+            return BoolKind
+        case binsonFloat:
+            return FloatKind
+        default:
+            return InvalidKind
+    }
+}
+
+// Returns a Value holding the given Go value, converted as by Put.
+func ValueOf(value interface{}) (Value, error) {
+    f, err := toField(value)
+    if err != nil {
+        return Value{}, err
+    }
+    return Value{f}, nil
+}
+
+// Returns the kind of the value.
+func (v Value) Kind() Kind {
+    return kindOf(v.f)
+}
+
+// Returns the value as Binson, *BinsonArray, int64, string, []byte, bool or
+// float64. Returns nil for the zero Value.
+func (v Value) Interface() interface{} {
+    if v.f == nil {
+        return nil
+    }
+    return fieldValue(v.f)
+}
+
+// Writes the value to bytes.
+func (v Value) ToBytes() []byte {
+    if v.f == nil {
+        return nil
+    }
+    return v.f.appendTo(make([]byte, 0, v.f.encodedSize()))
+}
+
+func (v Value) GetBinson() (Binson, bool) {
+    obj, ok := v.f.(Binson)
+    return obj, ok
+}
+
+func (v Value) GetArray() (*BinsonArray, bool) {
+    obj, ok := v.f.(*BinsonArray)
+    return obj, ok
+}
+
+func (v Value) GetInt() (int64, bool) {
+    obj, ok := v.f.(binsonInt)
+    return int64(obj), ok
+}
+
+func (v Value) GetString() (string, bool) {
+    obj, ok := v.f.(binsonString)
+    return string(obj), ok
+}
+
+func (v Value) GetBytes() ([]byte, bool) {
+    obj, ok := v.f.(binsonBytes)
+    return []byte(obj), ok
+}
+
+func (v Value) GetBool() (bool, bool) {
+    obj, ok := v.f.(binsonBool)
+    return bool(obj), ok
+}
+
+func (v Value) GetFloat() (float64, bool) {
+    obj, ok := v.f.(binsonFloat)
+    return float64(obj), ok
+}
+
+// Returns the field with the given name.
+func (b Binson) Get(name string) (Value, bool) {
+    f, ok := b[binsonString(name)]
+    return Value{f}, ok
+}
+
+// Returns the element at the given index.
+func (a *BinsonArray) Get(index int) (Value, bool) {
+    if a.inRange(index){
+        return Value{}, false
+    }
+    return Value{(*a)[index]}, true
+}
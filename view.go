@@ -0,0 +1,453 @@
+package binson
+
+import (
+    "encoding/binary"
+    "fmt"
+    "io"
+    "math"
+)
+
+// View is a read-only Binson object backed by its serialized bytes. Values
+// are decoded on demand and nested objects and arrays are returned as views
+// aliasing the same bytes, so lookups do not allocate. The bytes must not be
+// modified while the view is in use.
+type View struct {
+    data []byte
+}
+
+// ArrayView is a read-only Binson array backed by its serialized bytes.
+type ArrayView struct {
+    data []byte
+}
+
+// Returns the number of bytes used by the length or integer of a type byte.
+func intSize(tag byte) int {
+    switch tag {
+        case binsonInteger1, binsonString1, binsonBytes1:
+            return 1
+        case binsonInteger2, binsonString2, binsonBytes2:
+            return 2
+        case binsonInteger4, binsonString4, binsonBytes4:
+            return 4
+        case binsonInteger8:
+            return 8
+        default:
+            return 0
+    }
+}
+
+// Reads a little-endian signed integer of the given size at pos.
+func readInt(data []byte, pos int, size int) (int64, bool) {
+    if pos < 0 || len(data) - pos < size {
+        return 0, false
+    }
+    switch size {
+        case 1:
+            return int64(int8(data[pos])), true
+        case 2:
+            return int64(int16(binary.LittleEndian.Uint16(data[pos:]))), true
+        case 4:
+            return int64(int32(binary.LittleEndian.Uint32(data[pos:]))), true
+        case 8:
+            return int64(binary.LittleEndian.Uint64(data[pos:])), true
+        default:
+            return 0, false
+    }
+}
+
+// Returns the start and end of the content of the string or bytes value at
+// pos.
+func readContent(data []byte, pos int) (int, int, error) {
+    size := intSize(data[pos])
+    length, ok := readInt(data, pos+1, size)
+    if !ok {
+        return 0, 0, io.ErrUnexpectedEOF
+    }
+    start := pos + 1 + size
+    if length < 0 {
+        return 0, 0, fmt.Errorf("Negative length: %d", length)
+    }
+    if int64(len(data) - start) < length {
+        return 0, 0, io.ErrUnexpectedEOF
+    }
+    return start, start + int(length), nil
+}
+
+// Returns the end of the value starting at pos.
+func skipValue(data []byte, pos int) (int, error) {
+    if len(data) <= pos {
+        return 0, io.ErrUnexpectedEOF
+    }
+    start := data[pos]
+    switch start {
+        case binsonBegin:
+            pos++
+            for {
+                if len(data) <= pos {
+                    return 0, io.ErrUnexpectedEOF
+                }
+                if data[pos] == binsonEnd {
+                    return pos + 1, nil
+                }
+                switch data[pos] {
+                    case binsonString1, binsonString2, binsonString4:
+                    default:
+                        return 0, fmt.Errorf("Expected field name, got: %X", data[pos])
+                }
+                var err error
+                if pos, err = skipValue(data, pos); err != nil {
+                    return 0, err
+                }
+                if pos, err = skipValue(data, pos); err != nil {
+                    return 0, err
+                }
+            }
+        case binsonBeginArray:
+            pos++
+            for {
+                if len(data) <= pos {
+                    return 0, io.ErrUnexpectedEOF
+                }
+                if data[pos] == binsonEndArray {
+                    return pos + 1, nil
+                }
+                var err error
+                if pos, err = skipValue(data, pos); err != nil {
+                    return 0, err
+                }
+            }
+        case binsonString1, binsonString2, binsonString4,
+            binsonBytes1, binsonBytes2, binsonBytes4:
+            _, end, err := readContent(data, pos)
+            return end, err
+        case binsonInteger1, binsonInteger2, binsonInteger4, binsonInteger8:
+            if len(data) - pos - 1 < intSize(start) {
+                return 0, io.ErrUnexpectedEOF
+            }
+            return pos + 1 + intSize(start), nil
+        case binsonTrue, binsonFalse:
+            return pos + 1, nil
+        case binsonDouble:
+            if len(data) - pos - 1 < 8 {
+                return 0, io.ErrUnexpectedEOF
+            }
+            return pos + 9, nil
+        default:
+            return 0, fmt.Errorf("Unknown byte: %X", start)
+    }
+}
+
+// Returns a view of the Binson object at the start of data. The object is
+// checked to be well-formed; any bytes after it are ignored.
+func NewView(data []byte) (View, error) {
+    if len(data) == 0 {
+        return View{}, io.ErrUnexpectedEOF
+    }
+    if data[0] != binsonBegin {
+        return View{}, fmt.Errorf("Got none Binson start: %X", data[0])
+    }
+    end, err := skipValue(data, 0)
+    if err != nil {
+        return View{}, err
+    }
+    return View{data[:end:end]}, nil
+}
+
+// Returns a view of the Binson array at the start of data. The array is
+// checked to be well-formed; any bytes after it are ignored.
+func NewArrayView(data []byte) (ArrayView, error) {
+    if len(data) == 0 {
+        return ArrayView{}, io.ErrUnexpectedEOF
+    }
+    if data[0] != binsonBeginArray {
+        return ArrayView{}, fmt.Errorf("Got none array start: %X", data[0])
+    }
+    end, err := skipValue(data, 0)
+    if err != nil {
+        return ArrayView{}, err
+    }
+    return ArrayView{data[:end:end]}, nil
+}
+
+func viewInt(data []byte, pos int) (int64, bool) {
+    if pos < 0 {
+        return 0, false
+    }
+    switch data[pos] {
+        case binsonInteger1, binsonInteger2, binsonInteger4, binsonInteger8:
+            return readInt(data, pos+1, intSize(data[pos]))
+        default:
+            return 0, false
+    }
+}
+
+func viewString(data []byte, pos int) ([]byte, bool) {
+    if pos < 0 {
+        return nil, false
+    }
+    switch data[pos] {
+        case binsonString1, binsonString2, binsonString4:
+            start, end, _ := readContent(data, pos)
+            return data[start:end:end], true
+        default:
+            return nil, false
+    }
+}
+
+func viewBytes(data []byte, pos int) ([]byte, bool) {
+    if pos < 0 {
+        return nil, false
+    }
+    switch data[pos] {
+        case binsonBytes1, binsonBytes2, binsonBytes4:
+            start, end, _ := readContent(data, pos)
+            return data[start:end:end], true
+        default:
+            return nil, false
+    }
+}
+
+func viewBool(data []byte, pos int) (bool, bool) {
+    if pos < 0 {
+        return false, false
+    }
+    switch data[pos] {
+        case binsonTrue:
+            return true, true
+        case binsonFalse:
+            return false, true
+        default:
+            return false, false
+    }
+}
+
+func viewFloat(data []byte, pos int) (float64, bool) {
+    if pos < 0 || data[pos] != binsonDouble {
+        return 0, false
+    }
+    return math.Float64frombits(binary.LittleEndian.Uint64(data[pos+1:])), true
+}
+
+func viewSub(data []byte, pos int, start byte) ([]byte, bool) {
+    if pos < 0 || data[pos] != start {
+        return nil, false
+    }
+    end, _ := skipValue(data, pos)
+    return data[pos:end:end], true
+}
+
+// Returns the position of the value of the field with the given name, or -1.
+func (v View) find(name string) int {
+    pos := 1
+    for pos < len(v.data) && v.data[pos] != binsonEnd {
+        start, end, _ := readContent(v.data, pos)
+        if string(v.data[start:end]) == name {
+            return end
+        }
+        pos, _ = skipValue(v.data, end)
+    }
+    return -1
+}
+
+// Returns the serialized bytes of this view.
+func (v View) Bytes() []byte {
+    return v.data
+}
+
+// Decodes this view to a Binson object.
+func (v View) ToBinson() (Binson, error) {
+    return Parse(v.data)
+}
+
+// Returns an ordered list of the field names of this view.
+func (v View) FieldNames() []string {
+    keys := []string{}
+    pos := 1
+    for pos < len(v.data) && v.data[pos] != binsonEnd {
+        start, end, _ := readContent(v.data, pos)
+        keys = append(keys, string(v.data[start:end]))
+        pos, _ = skipValue(v.data, end)
+    }
+    return keys
+}
+
+// Returns true if the view has a field with the given name.
+func (v View) ContainsKey(name string) bool {
+    return v.find(name) >= 0
+}
+
+func (v View) HasBinson(name string) bool {
+    _, ok := v.GetBinson(name)
+    return ok
+}
+
+func (v View) GetBinson(name string) (View, bool) {
+    data, ok := viewSub(v.data, v.find(name), binsonBegin)
+    return View{data}, ok
+}
+
+func (v View) HasArray(name string) bool {
+    _, ok := v.GetArray(name)
+    return ok
+}
+
+func (v View) GetArray(name string) (ArrayView, bool) {
+    data, ok := viewSub(v.data, v.find(name), binsonBeginArray)
+    return ArrayView{data}, ok
+}
+
+func (v View) HasInt(name string) bool {
+    _, ok := v.GetInt(name)
+    return ok
+}
+
+func (v View) GetInt(name string) (int64, bool) {
+    return viewInt(v.data, v.find(name))
+}
+
+func (v View) HasString(name string) bool {
+    _, ok := viewString(v.data, v.find(name))
+    return ok
+}
+
+// Returns the string field with the given name. Unlike other getters this
+// allocates a copy of the string.
+func (v View) GetString(name string) (string, bool) {
+    data, ok := viewString(v.data, v.find(name))
+    return string(data), ok
+}
+
+func (v View) HasBytes(name string) bool {
+    _, ok := v.GetBytes(name)
+    return ok
+}
+
+// Returns the bytes field with the given name. The returned slice aliases
+// the bytes of the view.
+func (v View) GetBytes(name string) ([]byte, bool) {
+    return viewBytes(v.data, v.find(name))
+}
+
+func (v View) HasBool(name string) bool {
+    _, ok := v.GetBool(name)
+    return ok
+}
+
+func (v View) GetBool(name string) (bool, bool) {
+    return viewBool(v.data, v.find(name))
+}
+
+func (v View) HasFloat(name string) bool {
+    _, ok := v.GetFloat(name)
+    return ok
+}
+
+func (v View) GetFloat(name string) (float64, bool) {
+    return viewFloat(v.data, v.find(name))
+}
+
+// Returns the position of the element at the given index, or -1.
+func (a ArrayView) find(index int) int {
+    if index < 0 {
+        return -1
+    }
+    pos := 1
+    for i := 0; pos < len(a.data) && a.data[pos] != binsonEndArray; i++ {
+        if i == index {
+            return pos
+        }
+        pos, _ = skipValue(a.data, pos)
+    }
+    return -1
+}
+
+// Returns the serialized bytes of this view.
+func (a ArrayView) Bytes() []byte {
+    return a.data
+}
+
+// Decodes this view to a Binson array.
+func (a ArrayView) ToArray() (*BinsonArray, error) {
+    return ParseArray(a.data)
+}
+
+// Returns the number of elements of this array.
+func (a ArrayView) Size() int {
+    size := 0
+    pos := 1
+    for pos < len(a.data) && a.data[pos] != binsonEndArray {
+        pos, _ = skipValue(a.data, pos)
+        size++
+    }
+    return size
+}
+
+func (a ArrayView) HasBinson(index int) bool {
+    _, ok := a.GetBinson(index)
+    return ok
+}
+
+func (a ArrayView) GetBinson(index int) (View, bool) {
+    data, ok := viewSub(a.data, a.find(index), binsonBegin)
+    return View{data}, ok
+}
+
+func (a ArrayView) HasArray(index int) bool {
+    _, ok := a.GetArray(index)
+    return ok
+}
+
+func (a ArrayView) GetArray(index int) (ArrayView, bool) {
+    data, ok := viewSub(a.data, a.find(index), binsonBeginArray)
+    return ArrayView{data}, ok
+}
+
+func (a ArrayView) HasInt(index int) bool {
+    _, ok := a.GetInt(index)
+    return ok
+}
+
+func (a ArrayView) GetInt(index int) (int64, bool) {
+    return viewInt(a.data, a.find(index))
+}
+
+func (a ArrayView) HasString(index int) bool {
+    _, ok := viewString(a.data, a.find(index))
+    return ok
+}
+
+// Returns the string element at the given index. Unlike other getters this
+// allocates a copy of the string.
+func (a ArrayView) GetString(index int) (string, bool) {
+    data, ok := viewString(a.data, a.find(index))
+    return string(data), ok
+}
+
+func (a ArrayView) HasBytes(index int) bool {
+    _, ok := a.GetBytes(index)
+    return ok
+}
+
+// Returns the bytes element at the given index. The returned slice aliases
+// the bytes of the view.
+func (a ArrayView) GetBytes(index int) ([]byte, bool) {
+    return viewBytes(a.data, a.find(index))
+}
+
+func (a ArrayView) HasBool(index int) bool {
+    _, ok := a.GetBool(index)
+    return ok
+}
+
+func (a ArrayView) GetBool(index int) (bool, bool) {
+    return viewBool(a.data, a.find(index))
+}
+
+func (a ArrayView) HasFloat(index int) bool {
+    _, ok := a.GetFloat(index)
+    return ok
+}
+
+func (a ArrayView) GetFloat(index int) (float64, bool) {
+    return viewFloat(a.data, a.find(index))
+}